@@ -31,6 +31,7 @@ import (
 	"sigs.k8s.io/metrics-server/pkg/api"
 
 	generatedopenapi "github.com/apache/skywalking-swck/adapter/pkg/api/generated/openapi"
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
 	swckprov "github.com/apache/skywalking-swck/adapter/pkg/provider"
 )
 
@@ -46,6 +47,22 @@ type Adapter struct {
 	Message string
 	// Namespace groups metrics into a single set in case of duplicated metric name
 	Namespace string
+	// ConfigFile is the path of the configuration file
+	ConfigFile string
+	// CacheTTL is the duration to cache the responses of OAP cluster
+	CacheTTL time.Duration
+	// CacheStaleTTL is the duration to serve expired responses while refreshing them
+	CacheStaleTTL time.Duration
+	// Auth is the credentials and TLS settings to connect OAP cluster
+	Auth config.AuthConfig
+	// OAPTimeout bounds the queries to OAP cluster without deadlines
+	OAPTimeout time.Duration
+	// OAPRetries is the number of retries of the failed queries to OAP cluster
+	OAPRetries int
+	// OAPMaxIdleConns is the number of idle connections kept to OAP cluster
+	OAPMaxIdleConns int
+	// Registry is where to persist the metric registries
+	Registry swckprov.RegistryOptions
 }
 
 func main() {
@@ -61,21 +78,80 @@ func main() {
 	cmd.OpenAPIConfig.Info.Version = "1.0.0"
 
 	cmd.Flags().StringVar(&cmd.Message, "msg", "starting adapter...", "startup message")
-	cmd.Flags().StringVar(&cmd.BaseURL, "oap-addr", "http://oap:12800/graphql", "the address of OAP cluster")
+	cmd.Flags().StringVar(&cmd.BaseURL, "oap-addr", "http://oap:12800/graphql",
+		"the address of OAP cluster, the OAP flags are ignored if the backends are defined in the configuration file")
 	cmd.Flags().StringVar(&cmd.MetricRegex, "metric-filter-regex", "", "a regular expression to filter metrics retrieved from OAP cluster")
-	cmd.Flags().StringVar(&cmd.Namespace, "namespace", "skywalking.apache.org", "a prefix to which metrics are appended. The format is 'namespace|metric_name'")
+	cmd.Flags().StringVar(&cmd.Namespace, "namespace", "skywalking.apache.org", "a prefix to which metrics are appended. The format is 'namespace|metric_name'. "+
+		"An empty namespace exposes the metric names as they are")
 	cmd.Flags().DurationVar(&cmd.RefreshRegistryInterval, "refresh-interval", 10*time.Second,
 		"the interval at which to update the cache of available metrics from OAP cluster")
+	cmd.Flags().StringVar(&cmd.ConfigFile, "config", "",
+		"the path of the configuration file. Omit this flag to use the default configuration values")
+	cmd.Flags().DurationVar(&cmd.CacheTTL, "cache-ttl", 15*time.Second,
+		"the duration to cache the responses of OAP cluster, 0 disables the cache")
+	cmd.Flags().DurationVar(&cmd.CacheStaleTTL, "cache-stale-ttl", 0,
+		"the duration to serve the expired responses of OAP cluster while refreshing them in the background")
+	cmd.Flags().StringVar(&cmd.Auth.UsernameFile, "oap-username-file", "", "the file containing the username of OAP cluster")
+	cmd.Flags().StringVar(&cmd.Auth.PasswordFile, "oap-password-file", "", "the file containing the password of OAP cluster")
+	cmd.Flags().StringVar(&cmd.Auth.TokenFile, "oap-token-file", "",
+		"the file containing the bearer token of OAP cluster, which takes precedence over the username and password")
+	cmd.Flags().StringVar(&cmd.Auth.CAFile, "oap-ca-file", "", "the CA bundle to verify the certificate of OAP cluster")
+	cmd.Flags().StringVar(&cmd.Auth.CertFile, "oap-cert-file", "", "the client certificate to connect OAP cluster")
+	cmd.Flags().StringVar(&cmd.Auth.KeyFile, "oap-key-file", "", "the key of the client certificate to connect OAP cluster")
+	cmd.Flags().DurationVar(&cmd.OAPTimeout, "oap-timeout", 10*time.Second,
+		"the timeout of the queries to OAP cluster whose requests have no deadlines, such as syncing the metric registry")
+	cmd.Flags().IntVar(&cmd.OAPRetries, "oap-retries", 2,
+		"the number of retries of the queries to OAP cluster which fail due to connection errors or 5xx responses")
+	cmd.Flags().IntVar(&cmd.OAPMaxIdleConns, "oap-max-idle-conns", 16, "the number of idle connections kept to OAP cluster")
+	cmd.Flags().StringVar(&cmd.Registry.File, "registry-file", "",
+		"the file to persist the metric registries, which are served on boot until fresh syncs succeed")
+	cmd.Flags().StringVar(&cmd.Registry.ConfigMap, "registry-configmap", "",
+		"the ConfigMap in the form of 'namespace/name' to persist the metric registries, which are served on boot until fresh syncs succeed")
 	logs.AddFlags(cmd.Flags())
 	if err := cmd.Flags().Parse(os.Args); err != nil {
 		klog.Fatalf("failed to parse arguments: %v", err)
 	}
 
-	p, err := swckprov.NewProvider(cmd.BaseURL, cmd.MetricRegex, cmd.RefreshRegistryInterval, cmd.Namespace)
+	cfg := &config.Config{}
+	if cmd.ConfigFile != "" {
+		var err error
+		if cfg, err = config.ParseFile(cmd.ConfigFile); err != nil {
+			klog.Fatalf("unable to load the config file: %v", err)
+		}
+	}
+
+	client, err := cmd.DynamicClient()
+	if err != nil {
+		klog.Fatalf("unable to construct dynamic client: %v", err)
+	}
+	mapper, err := cmd.RESTMapper()
+	if err != nil {
+		klog.Fatalf("unable to construct discovery REST mapper: %v", err)
+	}
+
+	if len(cfg.Backends) == 0 {
+		cfg.Backends = []config.BackendConfig{{
+			Name:              "default",
+			Address:           cmd.BaseURL,
+			MetricFilterRegex: cmd.MetricRegex,
+			RefreshInterval:   cmd.RefreshRegistryInterval,
+			Auth:              cmd.Auth,
+			Timeout:           cmd.OAPTimeout,
+			Retries:           &cmd.OAPRetries,
+			MaxIdleConns:      cmd.OAPMaxIdleConns,
+		}}
+	}
+	p, err := swckprov.NewProvider(cmd.Namespace, client, mapper, cfg, cmd.CacheTTL, cmd.CacheStaleTTL, cmd.Registry)
 	if err != nil {
 		klog.Fatalf("unable to build p: %v", err)
 	}
+	cmd.WithCustomMetrics(p)
 	cmd.WithExternalMetrics(p)
+	serverConfig, err := cmd.Config()
+	if err != nil {
+		klog.Fatalf("unable to construct the server config: %v", err)
+	}
+	serverConfig.GenericConfig.AddReadyzChecks(p)
 
 	klog.Info(cmd.Message)
 	if err := cmd.Run(wait.NeverStop); err != nil {
@@ -0,0 +1,158 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the adapter which is loaded from the file specified by `--config`
+type Config struct {
+	// Query is the default options to query metric values from OAP cluster
+	Query QueryConfig `yaml:"query"`
+	// Metrics overrides the query options of particular metrics
+	Metrics []MetricConfig `yaml:"metrics"`
+	// Expressions are the named MQE(Metrics Query Expression) served as metrics
+	Expressions []ExpressionConfig `yaml:"expressions"`
+	// Backends are the OAP clusters serving the metrics, the command line flags define the only backend if it's empty
+	Backends []BackendConfig `yaml:"backends"`
+	// Access grants the namespaces access to the services, all the services are accessible if it's empty
+	Access []AccessConfig `yaml:"access"`
+}
+
+// AccessConfig grants the HPAs in the namespaces access to the services matching the names or the layers
+type AccessConfig struct {
+	// Namespaces are the Kubernetes namespaces, `*` matches all namespaces
+	Namespaces []string `yaml:"namespaces"`
+	// Services are the regular expressions fully matching the service names
+	Services []string `yaml:"services"`
+	// Layers are the layers of the services, such as GENERAL and MESH
+	Layers []string `yaml:"layers"`
+}
+
+// BackendConfig is an OAP cluster which serves the metrics
+type BackendConfig struct {
+	// Name identifies the backend in logs and metrics
+	Name string `yaml:"name"`
+	// Address is the GraphQL address of OAP cluster
+	Address string `yaml:"address"`
+	// MetricFilterRegex filters the metrics retrieved from OAP cluster
+	MetricFilterRegex string `yaml:"metricFilterRegex"`
+	// RefreshInterval is the interval to update the metric registry from OAP cluster
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+	// Namespace is the prefix of the metric names, which routes the external metrics to the backend.
+	// It falls back to the `--namespace` flag if it's nil, and an empty one exposes the metric names as they are.
+	Namespace *string `yaml:"namespace"`
+	// KubernetesNamespaces are the namespaces of HPAs routed to the backend, all namespaces if it's empty
+	KubernetesNamespaces []string `yaml:"kubernetesNamespaces"`
+	// Auth is the credentials and TLS settings to connect OAP cluster
+	Auth AuthConfig `yaml:"auth"`
+	// Timeout bounds the queries without deadlines, such as the registry syncs
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries is the number of retries of the failed queries, it defaults to 2
+	Retries *int `yaml:"retries"`
+	// MaxIdleConns is the number of idle connections kept to OAP cluster
+	MaxIdleConns int `yaml:"maxIdleConns"`
+}
+
+// AuthConfig is the credentials and TLS settings to connect OAP cluster. All of them are paths of files, which
+// are usually mounted from Secrets, and reloaded once they change.
+type AuthConfig struct {
+	// UsernameFile and PasswordFile are the credentials of basic authentication
+	UsernameFile string `yaml:"usernameFile"`
+	PasswordFile string `yaml:"passwordFile"`
+	// TokenFile is the bearer token, which takes precedence over basic authentication
+	TokenFile string `yaml:"tokenFile"`
+	// CAFile is the CA bundle to verify the certificate of OAP cluster
+	CAFile string `yaml:"caFile"`
+	// CertFile and KeyFile are the client certificate for mutual TLS
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// QueryConfig defines how to query and reduce metric values, the empty fields fall back to the upper level
+type QueryConfig struct {
+	// Window is the length of the time range to query
+	Window time.Duration `yaml:"window"`
+	// Step is the precision of the time range, one of SECOND, MINUTE, HOUR and DAY
+	Step string `yaml:"step"`
+	// Aggregation reduces the values in the time range to a single one, one of last, avg, max, min and sum
+	Aggregation string `yaml:"aggregation"`
+	// StalePolicy serves the values whose latest complete bucket is empty, one of error, last and default
+	StalePolicy string `yaml:"stalePolicy"`
+	// StaleValue is the value served by the default stale policy
+	StaleValue *float64 `yaml:"staleValue"`
+}
+
+// MetricConfig is the rule to serve a metric of OAP cluster
+type MetricConfig struct {
+	// Name is the metric name in OAP cluster
+	Name string `yaml:"name"`
+	// As is the metric name exposed to Kubernetes, the rule applies to the default name `namespace|name` if it's empty.
+	// A metric could be exposed in several names by rules with different aliases.
+	As          string `yaml:"as"`
+	QueryConfig `yaml:",inline"`
+	// Scale is multiplied to the metric value, such as 0.01 to convert SLA from 1/10000 to percentage
+	Scale float64 `yaml:"scale"`
+	// Scope overrides the scope of the entity, which is inferred from the entity fields by default
+	Scope string `yaml:"scope"`
+	// Label is the default label of multi-labels metrics
+	Label string `yaml:"label"`
+	// Entity renders the entity fields from the selector labels
+	Entity EntityConfig `yaml:"entity"`
+}
+
+// EntityConfig defines the templates to render the entity fields, such as `{{ .Labels.app }}|{{ .Namespace }}`.
+// The fields are picked from the selector labels if the templates are empty.
+type EntityConfig struct {
+	Service      string `yaml:"service"`
+	Instance     string `yaml:"instance"`
+	Endpoint     string `yaml:"endpoint"`
+	DestService  string `yaml:"destService"`
+	DestInstance string `yaml:"destInstance"`
+	DestEndpoint string `yaml:"destEndpoint"`
+}
+
+// ParseFile loads the configuration from the path
+func ParseFile(path string) (*Config, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the configuration file: %v", err)
+	}
+	defer fd.Close()
+
+	cfg := Config{}
+
+	if err = yaml.NewDecoder(fd).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("could not decode configuration file: %v", err)
+	}
+
+	return &cfg, nil
+}
+
+// ExpressionConfig is a MQE(Metrics Query Expression) evaluated by OAP cluster
+type ExpressionConfig struct {
+	// MetricConfig names the expression and sets its query options
+	MetricConfig `yaml:",inline"`
+	// Expression is the MQE, such as `service_sla / 100`
+	Expression string `yaml:"expression"`
+}
@@ -0,0 +1,94 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFileExpressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ExpressionConfig
+		wantErr bool
+	}{
+		{
+			name: "named expressions",
+			content: `
+expressions:
+  - name: service_error_rate
+    expression: 100 - service_sla / 100
+  - name: service_slowest_endpoint_resp_time
+    as: slowest-endpoint
+    expression: top_n(endpoint_resp_time, 1, des)
+    aggregation: max
+    window: 5m
+    scale: 0.001
+    label: "2"
+    entity:
+      service: "{{ .Labels.app }}"
+`,
+			want: []ExpressionConfig{
+				{MetricConfig: MetricConfig{Name: "service_error_rate"}, Expression: "100 - service_sla / 100"},
+				{
+					MetricConfig: MetricConfig{
+						Name:        "service_slowest_endpoint_resp_time",
+						As:          "slowest-endpoint",
+						QueryConfig: QueryConfig{Aggregation: "max", Window: 5 * time.Minute},
+						Scale:       0.001,
+						Label:       "2",
+						Entity:      EntityConfig{Service: "{{ .Labels.app }}"},
+					},
+					Expression: "top_n(endpoint_resp_time, 1, des)",
+				},
+			},
+		},
+		{name: "no expressions", content: "query:\n  window: 5m\n"},
+		{name: "malformed", content: "expressions: service_sla", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write the configuration: %v", err)
+			}
+			cfg, err := ParseFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(cfg.Expressions) != len(tt.want) {
+				t.Fatalf("ParseFile() = %d expressions, want %d", len(cfg.Expressions), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := cfg.Expressions[i]
+				if got.Name != want.Name || got.As != want.As || got.Expression != want.Expression ||
+					got.QueryConfig.Aggregation != want.QueryConfig.Aggregation || got.Window != want.Window ||
+					got.Scale != want.Scale || got.Label != want.Label || got.Entity != want.Entity {
+					t.Errorf("expression #%d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// allNamespaces matches all the namespaces in the access rules
+const allNamespaces string = "*"
+
+// layersTTL is the duration to cache the layers of services
+const layersTTL = time.Minute
+
+// accessRule grants the namespaces access to the services matching the names or the layers
+type accessRule struct {
+	namespaces map[string]bool
+	services   []*regexp.Regexp
+	layers     map[string]bool
+}
+
+func newAccessRule(ac config.AccessConfig) (*accessRule, error) {
+	if len(ac.Namespaces) == 0 {
+		return nil, fmt.Errorf("the namespaces are required")
+	}
+	r := &accessRule{
+		namespaces: make(map[string]bool, len(ac.Namespaces)),
+		layers:     make(map[string]bool, len(ac.Layers)),
+	}
+	for _, ns := range ac.Namespaces {
+		r.namespaces[ns] = true
+	}
+	for _, s := range ac.Services {
+		re, err := regexp.Compile("^(?:" + s + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid service regular expression %s: %v", s, err)
+		}
+		r.services = append(r.services, re)
+	}
+	for _, l := range ac.Layers {
+		r.layers[strings.ToUpper(l)] = true
+	}
+	return r, nil
+}
+
+func (r *accessRule) covers(namespace string) bool {
+	return r.namespaces[allNamespaces] || r.namespaces[namespace]
+}
+
+func (r *accessRule) matchService(service string) bool {
+	for _, re := range r.services {
+		if re.MatchString(service) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *accessRule) matchLayers(layers []string) bool {
+	for _, l := range layers {
+		if r.layers[strings.ToUpper(l)] {
+			return true
+		}
+	}
+	return false
+}
+
+// accessControl enforces the access rules, it allows everything if there are no rules
+type accessControl struct {
+	rules []*accessRule
+
+	lock   sync.Mutex
+	layers map[string]layersEntry
+}
+
+type layersEntry struct {
+	layers  []string
+	expires time.Time
+}
+
+func newAccessControl(acc []config.AccessConfig) (*accessControl, error) {
+	ac := &accessControl{layers: make(map[string]layersEntry)}
+	for i, c := range acc {
+		r, err := newAccessRule(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access rule #%d: %v", i, err)
+		}
+		ac.rules = append(ac.rules, r)
+	}
+	return ac, nil
+}
+
+// authorize returns a Forbidden error unless the namespace could query all the services of the backend.
+// The empty services are skipped.
+func (ac *accessControl) authorize(ctx context.Context, b *backend, namespace string, groupResource apischema.GroupResource,
+	metricName string, services ...string) error {
+	if len(ac.rules) == 0 {
+		return nil
+	}
+	for _, service := range services {
+		if service == "" {
+			continue
+		}
+		allowed, err := ac.allows(ctx, b, namespace, service)
+		if err != nil {
+			return apierr.NewInternalError(fmt.Errorf("unable to authorize service %s: %v", service, err))
+		}
+		if !allowed {
+			klog.Warningf("namespace %s is denied to query metric %s of service %s", namespace, metricName, service)
+			return apierr.NewForbidden(groupResource, metricName,
+				fmt.Errorf("namespace %s is not allowed to query service %s", namespace, service))
+		}
+	}
+	return nil
+}
+
+func (ac *accessControl) allows(ctx context.Context, b *backend, namespace, service string) (bool, error) {
+	var byLayers []*accessRule
+	for _, r := range ac.rules {
+		if !r.covers(namespace) {
+			continue
+		}
+		if r.matchService(service) {
+			return true, nil
+		}
+		if len(r.layers) > 0 {
+			byLayers = append(byLayers, r)
+		}
+	}
+	if len(byLayers) == 0 {
+		return false, nil
+	}
+	layers, err := ac.serviceLayers(ctx, b, service)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range byLayers {
+		if r.matchLayers(layers) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// serviceLayers returns the layers of the service in the backend, which are cached for a while
+func (ac *accessControl) serviceLayers(ctx context.Context, b *backend, service string) ([]string, error) {
+	key := b.name + "/" + service
+	now := time.Now()
+	ac.lock.Lock()
+	entry, ok := ac.layers[key]
+	ac.lock.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.layers, nil
+	}
+
+	layers, err := b.oap.serviceLayers(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	for k, e := range ac.layers {
+		if now.After(e.expires) {
+			delete(ac.layers, k)
+		}
+	}
+	ac.layers[key] = layersEntry{layers: layers, expires: now.Add(layersTTL)}
+	return layers, nil
+}
@@ -0,0 +1,94 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+func TestAccessControl(t *testing.T) {
+	rules := []config.AccessConfig{
+		{Namespaces: []string{"music"}, Services: []string{"songs", "agent::.*"}},
+		{Namespaces: []string{"mesh"}, Layers: []string{"mesh"}},
+		{Namespaces: []string{"*"}, Services: []string{"public"}},
+	}
+	b := &backend{name: "oap"}
+	tests := []struct {
+		name      string
+		rules     []config.AccessConfig
+		namespace string
+		services  []string
+		wantErr   bool
+	}{
+		{name: "no rules allow everything", namespace: "music", services: []string{"anything"}},
+		{name: "the service matches", rules: rules, namespace: "music", services: []string{"songs"}},
+		{name: "the service matches the regular expression", rules: rules, namespace: "music", services: []string{"agent::songs"}},
+		{name: "the regular expression matches fully", rules: rules, namespace: "music", services: []string{"songs-v2"}, wantErr: true},
+		{name: "the namespace is not granted", rules: rules, namespace: "books", services: []string{"songs"}, wantErr: true},
+		{name: "all the namespaces are granted", rules: rules, namespace: "books", services: []string{"public"}},
+		{name: "the layer matches", rules: rules, namespace: "mesh", services: []string{"istio"}},
+		{name: "the layer doesn't match", rules: rules, namespace: "mesh", services: []string{"songs"}, wantErr: true},
+		{name: "the empty services are skipped", rules: rules, namespace: "music", services: []string{"songs", ""}},
+		{name: "any service is denied", rules: rules, namespace: "music", services: []string{"songs", "books"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, err := newAccessControl(tt.rules)
+			if err != nil {
+				t.Fatalf("newAccessControl() error = %v", err)
+			}
+			// the layers are served by the cache instead of OAP
+			expires := time.Now().Add(time.Minute)
+			ac.layers["oap/istio"] = layersEntry{layers: []string{"MESH"}, expires: expires}
+			ac.layers["oap/songs"] = layersEntry{layers: []string{"GENERAL"}, expires: expires}
+
+			err = ac.authorize(context.Background(), b, tt.namespace, NsGroupResource, "service_cpm", tt.services...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierr.IsForbidden(err) {
+				t.Errorf("authorize() error = %v, want Forbidden", err)
+			}
+		})
+	}
+}
+
+func TestNewAccessControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []config.AccessConfig
+		wantErr bool
+	}{
+		{name: "valid", rules: []config.AccessConfig{{Namespaces: []string{"*"}, Services: []string{"songs|books"}}}},
+		{name: "no namespaces", rules: []config.AccessConfig{{Services: []string{"songs"}}}, wantErr: true},
+		{name: "invalid regular expression", rules: []config.AccessConfig{{Namespaces: []string{"*"}, Services: []string{"songs("}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAccessControl(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("newAccessControl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// backend is an OAP cluster serving the metrics, it keeps its own metric registry
+type backend struct {
+	name                    string
+	oap                     *oapClient
+	regex                   string
+	refreshRegistryInterval time.Duration
+	// namespace is the prefix of the metric names exposed to Kubernetes
+	namespace string
+	// kubeNamespaces are the namespaces of HPAs routed to the backend, all namespaces if it's empty
+	kubeNamespaces map[string]bool
+
+	lock          sync.RWMutex
+	metricDefines []*swctlapi.MetricDefinition
+	// ready is true once the metric registry has been synced with OAP or restored from the store
+	ready atomic.Bool
+	// store persists the metric registry, it's nil if the registry is not persisted
+	store registryStore
+	// persisted is the last persisted registry, which is only accessed by the sync loop
+	persisted string
+}
+
+func newBackend(bc config.BackendConfig, defaultNamespace string, store registryStore) (*backend, error) {
+	if bc.Address == "" {
+		return nil, fmt.Errorf("the address of OAP is required")
+	}
+	maxIdleConns := bc.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = defaultOAPMaxIdleConns
+	}
+	httpClient, err := newHTTPClient(bc.Address, bc.Auth, maxIdleConns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the client of OAP: %v", err)
+	}
+	timeout, retries := bc.Timeout, defaultOAPRetries
+	if timeout <= 0 {
+		timeout = defaultOAPTimeout
+	}
+	if bc.Retries != nil {
+		if *bc.Retries < 0 {
+			return nil, fmt.Errorf("invalid retries: %d", *bc.Retries)
+		}
+		retries = *bc.Retries
+	}
+	b := &backend{
+		name:                    bc.Name,
+		oap:                     newOAPClient(bc.Name, bc.Address, httpClient, timeout, retries),
+		regex:                   bc.MetricFilterRegex,
+		refreshRegistryInterval: bc.RefreshInterval,
+		namespace:               defaultNamespace,
+		kubeNamespaces:          make(map[string]bool, len(bc.KubernetesNamespaces)),
+		store:                   store,
+	}
+	if bc.Namespace != nil {
+		b.namespace = *bc.Namespace
+	}
+	if b.refreshRegistryInterval <= 0 {
+		b.refreshRegistryInterval = 10 * time.Second
+	}
+	for _, ns := range bc.KubernetesNamespaces {
+		b.kubeNamespaces[ns] = true
+	}
+	return b, nil
+}
+
+// serves tells whether the HPAs in the Kubernetes namespace are routed to the backend,
+// and whether the backend lists the namespace explicitly
+func (b *backend) serves(namespace string) (serves, explicit bool) {
+	if len(b.kubeNamespaces) == 0 {
+		return true, false
+	}
+	return b.kubeNamespaces[namespace], b.kubeNamespaces[namespace]
+}
+
+// metricName returns the name exposed to Kubernetes of the metric in OAP
+func (b *backend) metricName(name string) string {
+	if b.namespace == "" {
+		return name
+	}
+	return strings.Join([]string{b.namespace, name}, "|")
+}
+
+func (b *backend) sync() {
+	b.restore()
+	go wait.Until(func() {
+		if err := b.updateMetrics(); err != nil {
+			registrySyncs.WithLabelValues(b.name, "failure").Inc()
+			klog.Errorf("failed to update metrics of backend %s: %v", b.name, err)
+			return
+		}
+		registrySyncs.WithLabelValues(b.name, "success").Inc()
+		recordRegistrySync(b.name)
+		b.ready.Store(true)
+	}, b.refreshRegistryInterval, wait.NeverStop)
+}
+
+// restore serves the persisted metric registry until a fresh sync succeeds
+func (b *backend) restore() {
+	if b.store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), registryStoreTimeout)
+	defer cancel()
+	mdd, err := b.store.load(ctx, b.name)
+	if err != nil {
+		klog.Errorf("failed to restore the metric registry of backend %s: %v", b.name, err)
+		return
+	}
+	if len(mdd) == 0 {
+		return
+	}
+	klog.Infof("Restore %d metrics of backend %s", len(mdd), b.name)
+	b.lock.Lock()
+	b.metricDefines = mdd
+	b.lock.Unlock()
+	b.persisted = display(mdd)
+	registrySize.WithLabelValues(b.name).Set(float64(len(mdd)))
+	b.ready.Store(true)
+}
+
+// persist saves the metric registry if it changes
+func (b *backend) persist(mdd []*swctlapi.MetricDefinition) {
+	if b.store == nil {
+		return
+	}
+	content := display(mdd)
+	if content == b.persisted {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), registryStoreTimeout)
+	defer cancel()
+	if err := b.store.save(ctx, b.name, mdd); err != nil {
+		klog.Errorf("failed to persist the metric registry of backend %s: %v", b.name, err)
+		return
+	}
+	b.persisted = content
+}
+
+func (b *backend) updateMetrics() error {
+	mdd, err := b.oap.listMetrics(context.Background(), b.regex)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Get service metrics of backend %s: %s", b.name, display(mdd))
+	if len(mdd) > 0 {
+		b.lock.Lock()
+		b.metricDefines = mdd
+		b.lock.Unlock()
+		registrySize.WithLabelValues(b.name).Set(float64(len(mdd)))
+		b.persist(mdd)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// cachedValues is the response of OAP with the end of its time range
+type cachedValues struct {
+	values []metricsValues
+	// timeSeries tells whether the values are points of the time range
+	timeSeries bool
+	end        time.Time
+}
+
+type cacheEntry struct {
+	value     *cachedValues
+	fetchedAt time.Time
+}
+
+// queryCache caches the responses of OAP for a TTL, and coalesces the concurrent identical queries.
+// An expired entry is still served in the stale TTL, meanwhile it's refreshed in the background.
+type queryCache struct {
+	ttl      time.Duration
+	staleTTL time.Duration
+	lock     sync.Mutex
+	entries  map[string]*cacheEntry
+	group    singleflight.Group
+}
+
+func newQueryCache(ttl, staleTTL time.Duration) *queryCache {
+	c := &queryCache{
+		ttl:      ttl,
+		staleTTL: staleTTL,
+		entries:  make(map[string]*cacheEntry),
+	}
+	if ttl > 0 {
+		go wait.Until(c.evict, ttl+staleTTL, wait.NeverStop)
+	}
+	return c
+}
+
+// fetchFunc loads the value from OAP
+type fetchFunc func(ctx context.Context) (*cachedValues, error)
+
+// get returns the cached value of the key, or invokes fetch to load it. It returns once the ctx is done,
+// while the fetch goes on for the other callers sharing it until the deadline of the ctx, which is capped
+// by the timeout.
+func (c *queryCache) get(ctx context.Context, key string, timeout time.Duration, fetch fetchFunc) (*cachedValues, error) {
+	if c.ttl <= 0 {
+		cacheRequests.WithLabelValues("miss").Inc()
+		return c.load(ctx, key, timeout, fetch)
+	}
+
+	c.lock.Lock()
+	entry, exist := c.entries[key]
+	c.lock.Unlock()
+	if exist {
+		age := time.Since(entry.fetchedAt)
+		if age < c.ttl {
+			cacheRequests.WithLabelValues("hit").Inc()
+			return entry.value, nil
+		}
+		if age < c.ttl+c.staleTTL {
+			cacheRequests.WithLabelValues("stale").Inc()
+			go func() {
+				if _, err := c.load(context.Background(), key, timeout, fetch); err != nil {
+					klog.Errorf("failed to refresh the stale cache %s: %v", key, err)
+				}
+			}()
+			return entry.value, nil
+		}
+	}
+	cacheRequests.WithLabelValues("miss").Inc()
+	return c.load(ctx, key, timeout, fetch)
+}
+
+// load coalesces the identical fetches, which are not cancelled by any of the callers. The shared fetch
+// ends at the deadline of the first caller, or after the timeout whichever is earlier.
+func (c *queryCache) load(ctx context.Context, key string, timeout time.Duration, fetch fetchFunc) (*cachedValues, error) {
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := fetchContext(ctx, timeout)
+		defer cancel()
+		value, err := fetch(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+		if c.ttl > 0 {
+			c.lock.Lock()
+			c.entries[key] = &cacheEntry{value: value, fetchedAt: time.Now()}
+			c.lock.Unlock()
+		}
+		return value, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.Shared {
+			cacheCoalescedRequests.Inc()
+		}
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*cachedValues), nil
+	}
+}
+
+// fetchContext detaches the fetch from the cancellation of the caller, but keeps the deadline of the caller
+// capped by the timeout, so that an abandoned fetch doesn't hang forever.
+func fetchContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if timeout > 0 {
+		if capped := time.Now().Add(timeout); !ok || capped.Before(deadline) {
+			deadline, ok = capped, true
+		}
+	}
+	detached := context.WithoutCancel(ctx)
+	if !ok {
+		return context.WithCancel(detached)
+	}
+	return context.WithDeadline(detached, deadline)
+}
+
+func (c *queryCache) evict() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, entry := range c.entries {
+		if time.Since(entry.fetchedAt) >= c.ttl+c.staleTTL {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueryCacheTTL(t *testing.T) {
+	cachedEnd := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	fetchedEnd := cachedEnd.Add(time.Minute)
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		staleTTL time.Duration
+		// age is the age of the cached entry, there is no entry if it's zero
+		age         time.Duration
+		wantEnd     time.Time
+		wantFetches int32
+	}{
+		{name: "miss", ttl: time.Minute, wantEnd: fetchedEnd, wantFetches: 1},
+		{name: "hit in the TTL", ttl: time.Minute, age: 30 * time.Second, wantEnd: cachedEnd},
+		{name: "expired", ttl: time.Minute, age: 90 * time.Second, wantEnd: fetchedEnd, wantFetches: 1},
+		{
+			name: "stale in the stale TTL is refreshed in the background", ttl: time.Minute, staleTTL: time.Minute,
+			age: 90 * time.Second, wantEnd: cachedEnd, wantFetches: 1,
+		},
+		{
+			name: "expired after the stale TTL", ttl: time.Minute, staleTTL: time.Minute,
+			age: 150 * time.Second, wantEnd: fetchedEnd, wantFetches: 1,
+		},
+		{name: "disabled", age: 30 * time.Second, wantEnd: fetchedEnd, wantFetches: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newQueryCache(tt.ttl, tt.staleTTL)
+			if tt.age > 0 {
+				c.entries["key"] = &cacheEntry{value: &cachedValues{end: cachedEnd}, fetchedAt: time.Now().Add(-tt.age)}
+			}
+			var fetches atomic.Int32
+			got, err := c.get(context.Background(), "key", 0, func(context.Context) (*cachedValues, error) {
+				fetches.Add(1)
+				return &cachedValues{end: fetchedEnd}, nil
+			})
+			if err != nil {
+				t.Fatalf("get() error = %v", err)
+			}
+			if !got.end.Equal(tt.wantEnd) {
+				t.Errorf("get() = %s, want %s", got.end, tt.wantEnd)
+			}
+			// the stale entry is refreshed asynchronously
+			deadline := time.Now().Add(time.Second)
+			for fetches.Load() < tt.wantFetches && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if n := fetches.Load(); n != tt.wantFetches {
+				t.Errorf("fetches = %d, want %d", n, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestQueryCacheCoalesce(t *testing.T) {
+	c := newQueryCache(time.Minute, 0)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) (*cachedValues, error) {
+		fetches.Add(1)
+		<-release
+		return &cachedValues{}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.get(context.Background(), "key", 0, fetch); err != nil {
+				t.Errorf("get() error = %v", err)
+			}
+		}()
+	}
+	// a cancelled caller returns at once, and the fetch goes on for the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.get(ctx, "key", 0, fetch); !errors.Is(err, context.Canceled) {
+		t.Errorf("get() error = %v, want %v", err, context.Canceled)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+
+	// the errors are not cached
+	failed := errors.New("OAP is down")
+	if _, err := c.get(context.Background(), "failed", 0, func(context.Context) (*cachedValues, error) {
+		return nil, failed
+	}); !errors.Is(err, failed) {
+		t.Errorf("get() error = %v, want %v", err, failed)
+	}
+	if _, exist := c.entries["failed"]; exist {
+		t.Errorf("the error is cached")
+	}
+}
+
+func TestQueryCacheDeadline(t *testing.T) {
+	tests := []struct {
+		name string
+		// callerTimeout is the deadline of the caller, there is no deadline if it's zero
+		callerTimeout time.Duration
+		timeout       time.Duration
+		// want is the deadline of the fetch, there is no deadline if it's zero
+		want time.Duration
+	}{
+		{name: "capped by the timeout", callerTimeout: time.Hour, timeout: time.Second, want: time.Second},
+		{name: "the deadline of the caller", callerTimeout: time.Second, timeout: time.Hour, want: time.Second},
+		{name: "the timeout without the deadline of the caller", timeout: time.Second, want: time.Second},
+		{name: "the deadline of the caller without the timeout", callerTimeout: time.Second, want: time.Second},
+		{name: "neither"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.callerTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.callerTimeout)
+				defer cancel()
+			}
+			start := time.Now()
+			var deadline time.Time
+			var hasDeadline bool
+			if _, err := newQueryCache(0, 0).get(ctx, "key", tt.timeout, func(ctx context.Context) (*cachedValues, error) {
+				deadline, hasDeadline = ctx.Deadline()
+				return &cachedValues{}, nil
+			}); err != nil {
+				t.Fatalf("get() error = %v", err)
+			}
+			if hasDeadline != (tt.want > 0) {
+				t.Fatalf("the fetch has deadline %t, want %t", hasDeadline, tt.want > 0)
+			}
+			if got := deadline.Sub(start); hasDeadline && (got < tt.want-100*time.Millisecond || got > tt.want+100*time.Millisecond) {
+				t.Errorf("the deadline of the fetch is %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryCacheAbandonedFetch(t *testing.T) {
+	c := newQueryCache(time.Minute, 0)
+	done := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := c.get(ctx, "key", 50*time.Millisecond, func(ctx context.Context) (*cachedValues, error) {
+			<-ctx.Done()
+			done <- ctx.Err()
+			return nil, ctx.Err()
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("get() error = %v, want %v", err, context.Canceled)
+		}
+	}()
+	// the cancellation of the caller doesn't abort the fetch, but the timeout does
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("the fetch ends with %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the abandoned fetch never ends")
+	}
+}
@@ -0,0 +1,239 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/apis/custom_metrics"
+	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+	"sigs.k8s.io/custom-metrics-apiserver/pkg/provider/helpers"
+)
+
+// serviceNameAnnotation is set by the java agent injector to override the service name of the agent
+const serviceNameAnnotation string = "agent.skywalking.apache.org/agent.service_name"
+
+var (
+	PodGroupResource = apischema.GroupResource{Resource: "pods"}
+	// WorkloadGroupResources are the objects whose custom metrics are resolved to SkyWalking services
+	WorkloadGroupResources = []apischema.GroupResource{
+		{Group: "apps", Resource: "deployments"},
+		{Group: "apps", Resource: "statefulsets"},
+		{Group: "apps", Resource: "daemonsets"},
+		{Group: "apps", Resource: "replicasets"},
+	}
+)
+
+func (p *externalMetricsProvider) GetMetricByName(ctx context.Context, name types.NamespacedName, info apiprovider.CustomMetricInfo,
+	metricSelector labels.Selector) (_ *custom_metrics.MetricValue, err error) {
+	metric := unknownMetric
+	defer func(start time.Time) {
+		observeRequest(apiCustomMetrics, metric, start, err)
+	}(time.Now())
+	b, md, rule := p.route(info.Metric, name.Namespace)
+	if md == nil {
+		klog.Errorf("%s is missing in OAP", info.Metric)
+		return nil, apiprovider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+	}
+	metric = info.Metric
+	resClient, err := p.resourceClient(name.Namespace, info)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := resClient.Get(ctx, name.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return p.getObjectMetric(ctx, b, md, rule, obj, info, metricSelector)
+}
+
+func (p *externalMetricsProvider) GetMetricBySelector(ctx context.Context, namespace string, selector labels.Selector,
+	info apiprovider.CustomMetricInfo, metricSelector labels.Selector) (_ *custom_metrics.MetricValueList, err error) {
+	metric := unknownMetric
+	defer func(start time.Time) {
+		observeRequest(apiCustomMetrics, metric, start, err)
+	}(time.Now())
+	b, md, rule := p.route(info.Metric, namespace)
+	if md == nil {
+		klog.Errorf("%s is missing in OAP", info.Metric)
+		return nil, apiprovider.NewMetricNotFoundError(info.GroupResource, info.Metric)
+	}
+	metric = info.Metric
+	resClient, err := p.resourceClient(namespace, info)
+	if err != nil {
+		return nil, err
+	}
+	objList, err := resClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &custom_metrics.MetricValueList{}
+	var missing int
+	err = apimeta.EachListItem(objList, func(item runtime.Object) error {
+		value, err := p.getObjectMetric(ctx, b, md, rule, item.(*unstructured.Unstructured), info, metricSelector)
+		if err != nil {
+			if apierr.IsNotFound(err) {
+				klog.V(4).Infof("skip object without metric %s: %v", info.Metric, err)
+				missing++
+				return nil
+			}
+			return err
+		}
+		res.Items = append(res.Items, *value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Items) == 0 && missing > 0 {
+		// the instances are usually named differently from the pods, if the agents are not injected by the operator
+		klog.Warningf("none of the %d %s selected by %s has metric %s", missing, info.GroupResource.String(), selector.String(), info.Metric)
+		return nil, apiprovider.NewMetricNotFoundForSelectorError(info.GroupResource, info.Metric, "", selector)
+	}
+	return res, nil
+}
+
+func (p *externalMetricsProvider) resourceClient(namespace string, info apiprovider.CustomMetricInfo) (dynamic.ResourceInterface, error) {
+	res, err := helpers.ResourceFor(p.mapper, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.Namespaced {
+		return p.client.Resource(res).Namespace(namespace), nil
+	}
+	return p.client.Resource(res), nil
+}
+
+func (p *externalMetricsProvider) getObjectMetric(ctx context.Context, b *backend, md *swctlapi.MetricDefinition, rule *metricRule, obj *unstructured.Unstructured,
+	info apiprovider.CustomMetricInfo, metricSelector labels.Selector) (*custom_metrics.MetricValue, error) {
+	var requirement labels.Requirements
+	if metricSelector != nil {
+		requirement, _ = metricSelector.Requirements()
+	}
+	entity, label, err := p.objectEntity(obj, rule, info, requirement)
+	if err != nil {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("invalid labels of metric %s: %v", md.Name, err))
+	}
+	if entity == nil {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("unable to resolve the service of %s %s/%s, "+
+			"either annotate it with %s or set label 'service'", info.GroupResource.String(),
+			obj.GetNamespace(), obj.GetName(), serviceNameAnnotation))
+	}
+	if err := p.access.authorize(ctx, b, obj.GetNamespace(), info.GroupResource, info.Metric, *entity.ServiceName); err != nil {
+		return nil, err
+	}
+	opts, err := p.resolveQueryOptions(rule, requirement)
+	if err != nil {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("invalid query options of metric %s: %v", md.Name, err))
+	}
+	var metricLabels []string
+	if label != nil && *label != "" {
+		metricLabels = []string{*label}
+	}
+	values, err := p.readMetricValues(ctx, b, md, entity, metricLabels, opts, info.GroupResource, info.Metric)
+	if err != nil {
+		return nil, err
+	}
+	name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	ref, err := helpers.ReferenceFor(p.mapper, name, info)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := newQuantity(values[0].value)
+	if err != nil {
+		return nil, apierr.NewInternalError(fmt.Errorf("invalid value of metric %s: %v", md.Name, err))
+	}
+
+	return &custom_metrics.MetricValue{
+		DescribedObject: ref,
+		Metric: custom_metrics.MetricIdentifier{
+			Name: info.Metric,
+		},
+		Timestamp: metav1.Time{
+			Time: values[0].timestamp,
+		},
+		Value: *quantity,
+	}, nil
+}
+
+// objectEntity maps a pod to a service instance named after the pod, which is the instance name set by the java agent
+// injector, and other workloads to their services. The service name is picked from the `service` metric label, the agent
+// annotation of the pod (template), or falls back to the name of the workload. The entity templates of the rule take
+// precedence over the mapping.
+// The entity is nil if the service name can't be resolved, and an error is returned if the labels are malformed.
+func (p *externalMetricsProvider) objectEntity(obj *unstructured.Unstructured, rule *metricRule, info apiprovider.CustomMetricInfo,
+	requirement labels.Requirements) (*swctlapi.Entity, *string, error) {
+	svc := &paramValue{key: "service"}
+	label := &paramValue{key: "label"}
+	endpoint := &paramValue{key: "endpoint"}
+	instance := &paramValue{key: "instance"}
+	if err := extractValue(requirement, svc, label, endpoint); err != nil {
+		return nil, nil, err
+	}
+	if rule != nil {
+		if err := rule.renderEntity(obj.GetNamespace(), requirement, svc, instance, endpoint); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	annotations := obj.GetAnnotations()
+	if info.GroupResource == PodGroupResource {
+		if instance.val == nil {
+			name := obj.GetName()
+			instance.val = &name
+		}
+	} else {
+		annotations, _, _ = unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+	}
+	if instance.val == nil {
+		empty := ""
+		instance.val = &empty
+	}
+	if svc.empty() {
+		service := annotations[serviceNameAnnotation]
+		if service == "" && info.GroupResource != PodGroupResource {
+			service = obj.GetName()
+		}
+		if service == "" {
+			return nil, nil, nil
+		}
+		svc.val = &service
+	}
+	if endpoint.val == nil {
+		empty := ""
+		endpoint.val = &empty
+	}
+
+	entity := newEntity(svc.val, instance.val, endpoint.val)
+	rule.applyDefaults(entity, label)
+	return entity, label.val, nil
+}
@@ -0,0 +1,231 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+var deploymentGroupResource = apischema.GroupResource{Group: "apps", Resource: "deployments"}
+
+// newObject builds a pod, or a deployment whose pod template has the annotations
+func newObject(kind, name string, podLabels, annotations map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "music", "labels": podLabels},
+	}}
+	if kind == "Pod" {
+		_ = unstructured.SetNestedMap(obj.Object, annotations, "metadata", "annotations")
+	} else {
+		obj.SetAPIVersion("apps/v1")
+		_ = unstructured.SetNestedMap(obj.Object, annotations, "spec", "template", "metadata", "annotations")
+	}
+	return obj
+}
+
+// entityOAP serves the values of the entities keyed by the service and instance joined with `/`,
+// the entities without values have no points
+func entityOAP(values map[string]float64) oapHandler {
+	return func(_ string, variables map[string]interface{}) (interface{}, string) {
+		condition, _ := variables["condition"].(map[string]interface{})
+		entity, _ := condition["entity"].(map[string]interface{})
+		key := entity["serviceName"].(string) + "/" + entity["serviceInstanceName"].(string)
+		v, exist := values[key]
+		if !exist {
+			return points(""), ""
+		}
+		return points("", value(v), value(0)), ""
+	}
+}
+
+// newCustomProvider serves the regular metrics of the OAP handler, and the Kubernetes objects by a fake client
+func newCustomProvider(t *testing.T, handler oapHandler, objects ...runtime.Object) *externalMetricsProvider {
+	t.Helper()
+	p := newTestProvider(t, handler, &config.Config{},
+		&swctlapi.MetricDefinition{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue},
+		&swctlapi.MetricDefinition{Name: "service_instance_cpm", Type: swctlapi.MetricsTypeRegularValue},
+	)
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(apischema.GroupVersionKind{Version: "v1", Kind: "Pod"}, apimeta.RESTScopeNamespace)
+	mapper.Add(apischema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, apimeta.RESTScopeNamespace)
+	p.mapper = mapper
+	p.client = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[apischema.GroupVersionResource]string{
+		{Version: "v1", Resource: "pods"}:                       "PodList",
+		{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+	}, objects...)
+	return p
+}
+
+func TestGetMetricByName(t *testing.T) {
+	annotated := map[string]interface{}{serviceNameAnnotation: "songs"}
+	objects := []runtime.Object{
+		newObject("Pod", "songs-0", nil, annotated),
+		newObject("Pod", "unannotated-0", nil, nil),
+		newObject("Deployment", "songs-deployment", nil, annotated),
+		newObject("Deployment", "books", nil, nil),
+	}
+	p := newCustomProvider(t, entityOAP(map[string]float64{
+		"songs/songs-0":             10,
+		"songs/":                    20,
+		"books/":                    30,
+		"overridden/songs-0":        40,
+		"unannotated/unannotated-0": 50,
+	}), objects...)
+	tests := []struct {
+		name          string
+		object        string
+		groupResource apischema.GroupResource
+		metric        string
+		selector      string
+		want          int64
+		wantKind      string
+		// wantErr checks the error, there should be no errors if it's nil
+		wantErr func(error) bool
+	}{
+		{
+			name: "the pod is mapped to the instance named after it", object: "songs-0", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", want: 10, wantKind: "Pod",
+		},
+		{
+			name: "the service label overrides the annotation", object: "songs-0", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", selector: "service=overridden", want: 40, wantKind: "Pod",
+		},
+		{
+			name: "the service label of the pod without the annotation", object: "unannotated-0", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", selector: "service=unannotated", want: 50, wantKind: "Pod",
+		},
+		{
+			name: "the workload is mapped by the annotation of its pod template", object: "songs-deployment",
+			groupResource: deploymentGroupResource, metric: "service_cpm", want: 20, wantKind: "Deployment",
+		},
+		{
+			name: "the workload without the annotation falls back to its name", object: "books",
+			groupResource: deploymentGroupResource, metric: "service_cpm", want: 30, wantKind: "Deployment",
+		},
+		{
+			name: "no service of the pod", object: "unannotated-0", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", wantErr: apierr.IsBadRequest,
+		},
+		{
+			name: "the metric is missing", object: "songs-0", groupResource: PodGroupResource,
+			metric: "missing_cpm", wantErr: apierr.IsNotFound,
+		},
+		{
+			name: "the object is missing", object: "songs-1", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", wantErr: apierr.IsNotFound,
+		},
+		{
+			name: "the instance has no values", object: "songs-0", groupResource: PodGroupResource,
+			metric: "service_instance_cpm", selector: "service=books", wantErr: apierr.IsNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			info := apiprovider.CustomMetricInfo{GroupResource: tt.groupResource, Namespaced: true, Metric: tt.metric}
+			got, err := p.GetMetricByName(context.Background(), types.NamespacedName{Namespace: "music", Name: tt.object}, info, selector)
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("GetMetricByName() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMetricByName() error = %v", err)
+			}
+			if v := got.Value.Value(); v != tt.want {
+				t.Errorf("GetMetricByName() = %d, want %d", v, tt.want)
+			}
+			if got.DescribedObject.Kind != tt.wantKind || got.DescribedObject.Name != tt.object || got.Metric.Name != tt.metric {
+				t.Errorf("GetMetricByName() describes %s %s of %s", got.DescribedObject.Kind, got.DescribedObject.Name, got.Metric.Name)
+			}
+		})
+	}
+}
+
+func TestGetMetricBySelector(t *testing.T) {
+	annotated := map[string]interface{}{serviceNameAnnotation: "songs"}
+	songs := map[string]interface{}{"app": "songs"}
+	objects := []runtime.Object{
+		newObject("Pod", "songs-0", songs, annotated),
+		newObject("Pod", "songs-1", songs, annotated),
+		newObject("Pod", "books-0", map[string]interface{}{"app": "books"}, map[string]interface{}{serviceNameAnnotation: "books"}),
+		newObject("Pod", "unannotated-0", map[string]interface{}{"app": "unannotated"}, nil),
+	}
+	p := newCustomProvider(t, entityOAP(map[string]float64{
+		"songs/songs-0": 10,
+		"songs/songs-1": 20,
+	}), objects...)
+	tests := []struct {
+		name     string
+		selector string
+		// want are the values by the pod names
+		want    map[string]int64
+		wantErr func(error) bool
+	}{
+		{name: "the pods of the selector", selector: "app=songs", want: map[string]int64{"songs-0": 10, "songs-1": 20}},
+		{name: "a pod without the service fails the others", wantErr: apierr.IsBadRequest},
+		{name: "none of the pods has the metric", selector: "app=books", wantErr: apierr.IsNotFound},
+		{name: "no pods are selected", selector: "app=movies", want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			info := apiprovider.CustomMetricInfo{GroupResource: PodGroupResource, Namespaced: true, Metric: "service_instance_cpm"}
+			got, err := p.GetMetricBySelector(context.Background(), "music", selector, info, labels.Everything())
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("GetMetricBySelector() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMetricBySelector() error = %v", err)
+			}
+			if len(got.Items) != len(tt.want) {
+				t.Fatalf("GetMetricBySelector() = %d items, want %d", len(got.Items), len(tt.want))
+			}
+			for _, item := range got.Items {
+				if v := item.Value.Value(); v != tt.want[item.DescribedObject.Name] {
+					t.Errorf("%s = %d, want %d", item.DescribedObject.Name, v, tt.want[item.DescribedObject.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"sync"
+	"time"
+
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsNamespace string = "skywalking_adapter"
+
+var (
+	cacheRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "cache",
+			Name:           "requests_total",
+			Help:           "Number of queries to the OAP response cache, partitioned by the result of hit, stale and miss.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	cacheCoalescedRequests = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "cache",
+			Name:           "coalesced_requests_total",
+			Help:           "Number of queries to OAP whose responses are shared with identical in-flight ones.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	requests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "requests_total",
+			Help:           "Number of requests to the metrics APIs, partitioned by the API, the metric and the reason of the result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"api", "metric", "reason"},
+	)
+	requestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Name:           "request_duration_seconds",
+			Help:           "Latency of the requests to the metrics APIs, partitioned by the API and the metric.",
+			Buckets:        metrics.ExponentialBuckets(0.005, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"api", "metric"},
+	)
+	oapErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "oap",
+			Name:           "errors_total",
+			Help:           "Number of failed GraphQL queries to OAP, partitioned by the backend and the operation.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"backend", "operation"},
+	)
+	staleValues = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "stale_values_total",
+			Help:           "Number of the stale metric values, partitioned by the metric, the stale policy and the reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"metric", "policy", "reason"},
+	)
+	registrySyncs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "registry",
+			Name:           "syncs_total",
+			Help:           "Number of the metric registry syncs with OAP, partitioned by the backend and the result of success and failure.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"backend", "result"},
+	)
+	registryLastSync = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "registry",
+			Name:           "last_sync_timestamp_seconds",
+			Help:           "Unix time of the last successful metric registry sync of the backend, the refresh age is the current time minus it.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"backend"},
+	)
+	registryAgeDesc = metrics.NewDesc(
+		metricsNamespace+"_registry_age_seconds",
+		"Seconds since the last successful metric registry sync of the backend.",
+		[]string{"backend"}, nil, metrics.ALPHA, "",
+	)
+	registrySize = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "registry",
+			Name:           "metrics",
+			Help:           "Number of the metrics in the registry of the backend.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"backend"},
+	)
+)
+
+// unknownMetric is the metric label of the requests to the metrics which are not served, so that the callers
+// can't grow the labels by arbitrary names
+const unknownMetric string = "unknown"
+
+// The APIs served by the adapter
+const (
+	apiExternalMetrics string = "external"
+	apiCustomMetrics   string = "custom"
+)
+
+func init() {
+	legacyregistry.MustRegister(cacheRequests, cacheCoalescedRequests, requests, requestDuration, oapErrors,
+		staleValues, registrySyncs, registryLastSync, registrySize)
+	legacyregistry.CustomMustRegister(&registryAgeCollector{})
+}
+
+// lastSyncs are the times of the last successful registry syncs by the backends
+var lastSyncs sync.Map
+
+// recordRegistrySync records a successful registry sync of the backend
+func recordRegistrySync(backend string) {
+	now := time.Now()
+	lastSyncs.Store(backend, now)
+	registryLastSync.WithLabelValues(backend).Set(float64(now.UnixNano()) / 1e9)
+}
+
+// registryAgeCollector reports the ages of the registries when they are scraped
+type registryAgeCollector struct {
+	metrics.BaseStableCollector
+}
+
+func (c *registryAgeCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- registryAgeDesc
+}
+
+func (c *registryAgeCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	lastSyncs.Range(func(backend, lastSync interface{}) bool {
+		ch <- metrics.NewLazyConstMetric(registryAgeDesc, metrics.GaugeValue, time.Since(lastSync.(time.Time)).Seconds(),
+			backend.(string))
+		return true
+	})
+}
+
+// observeRequest records the result and latency of a request to the metric
+func observeRequest(api, metric string, start time.Time, err error) {
+	reason := "Success"
+	if err != nil {
+		reason = string(apierr.ReasonForError(err))
+		if reason == "" {
+			reason = "Unknown"
+		}
+	}
+	requests.WithLabelValues(api, metric, reason).Inc()
+	requestDuration.WithLabelValues(api, metric).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-base/metrics/legacyregistry"
+	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+)
+
+// gathered returns the value of the metric of the legacy registry with the labels, and whether it is found.
+func gathered(t *testing.T, name string, labels map[string]string) (float64, bool) {
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather the metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.GetCounter() != nil:
+				return m.GetCounter().GetValue(), true
+			case m.GetGauge() != nil:
+				return m.GetGauge().GetValue(), true
+			case m.GetHistogram() != nil:
+				return float64(m.GetHistogram().GetSampleCount()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestObserveUnknownMetric(t *testing.T) {
+	p := &externalMetricsProvider{}
+	before, _ := gathered(t, "skywalking_adapter_requests_total", map[string]string{"api": apiExternalMetrics, "metric": unknownMetric})
+	for _, name := range []string{"no_such_metric", "another_missing_metric"} {
+		if _, err := p.GetExternalMetric(context.Background(), "default", labels.Everything(),
+			apiprovider.ExternalMetricInfo{Metric: name}); err == nil {
+			t.Fatalf("expected an error for %s", name)
+		}
+		if _, found := gathered(t, "skywalking_adapter_requests_total", map[string]string{"metric": name}); found {
+			t.Errorf("%s is recorded as a metric label", name)
+		}
+	}
+	after, _ := gathered(t, "skywalking_adapter_requests_total", map[string]string{"api": apiExternalMetrics, "metric": unknownMetric})
+	if after-before != 2 {
+		t.Errorf("expected 2 unknown requests, got %v", after-before)
+	}
+}
+
+func TestRegistryAge(t *testing.T) {
+	byBackend := map[string]string{"backend": "age-test"}
+	if _, found := gathered(t, "skywalking_adapter_registry_age_seconds", byBackend); found {
+		t.Fatal("the age is reported before any sync")
+	}
+	recordRegistrySync("age-test")
+	age, found := gathered(t, "skywalking_adapter_registry_age_seconds", byBackend)
+	if !found {
+		t.Fatal("the age isn't reported after the sync")
+	}
+	if age < 0 || age > 60 {
+		t.Errorf("unexpected age %v", age)
+	}
+	if ts, _ := gathered(t, "skywalking_adapter_registry_last_sync_timestamp_seconds", byBackend); ts == 0 {
+		t.Error("the last sync timestamp isn't set")
+	}
+}
@@ -0,0 +1,168 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// The defaults of the OAP client
+const (
+	defaultOAPTimeout      = 10 * time.Second
+	defaultOAPRetries      = 2
+	defaultOAPMaxIdleConns = 16
+)
+
+// oapClient sends GraphQL queries to OAP cluster. A query is bounded by the deadline of its context, or the
+// timeout of the client if the context has no deadline. The connection errors, 5xx and 429 responses
+// are retried with exponential backoff, while the GraphQL errors are not.
+type oapClient struct {
+	// name is the name of the backend
+	name    string
+	url     string
+	http    *http.Client
+	timeout time.Duration
+	retries int
+	backoff wait.Backoff
+	// legacyValues is set once OAP turns out not to tell the empty buckets of the metrics
+	legacyValues atomic.Bool
+}
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func newOAPClient(name, url string, httpClient *http.Client, timeout time.Duration, retries int) *oapClient {
+	return &oapClient{
+		name:    name,
+		url:     url,
+		http:    httpClient,
+		timeout: timeout,
+		retries: retries,
+		backoff: wait.Backoff{
+			Duration: 100 * time.Millisecond,
+			Factor:   2,
+			Jitter:   0.2,
+			Steps:    retries,
+			Cap:      2 * time.Second,
+		},
+	}
+}
+
+// query sends the query with the variables, decodes the data into the response,
+// and counts the failures by the operation
+func (c *oapClient) query(ctx context.Context, operation, query string, variables map[string]interface{},
+	response interface{}) error {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	body, err := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+	klog.V(5).Infof("%s request of backend %s: %s", operation, c.name, body)
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		var retriable bool
+		retriable, err = c.do(ctx, body, response)
+		if err == nil {
+			return nil
+		}
+		if !retriable || attempt >= c.retries || ctx.Err() != nil {
+			break
+		}
+		delay := backoff.Step()
+		klog.V(4).Infof("retry %s of backend %s in %s: %v", operation, c.name, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("%v, and gave up retrying: %v", err, ctx.Err())
+		case <-timer.C:
+			continue
+		}
+		break
+	}
+	oapErrors.WithLabelValues(c.name, operation).Inc()
+	return err
+}
+
+// do sends the request once, and tells whether the failure is retriable
+func (c *oapClient) do(ctx context.Context, body []byte, response interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json; charset=utf-8")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to read the response: %v", err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("unexpected status %s: %s", resp.Status, content)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %s: %s", resp.Status, content)
+	}
+	klog.V(5).Infof("response of backend %s: %s", c.name, content)
+
+	var gr graphqlResponse
+	if err := json.Unmarshal(content, &gr); err != nil {
+		return false, fmt.Errorf("failed to decode the response: %v", err)
+	}
+	if len(gr.Errors) > 0 {
+		messages := make([]string, 0, len(gr.Errors))
+		for _, e := range gr.Errors {
+			messages = append(messages, e.Message)
+		}
+		return false, fmt.Errorf("graphql: %s", strings.Join(messages, "; "))
+	}
+	if err := json.Unmarshal(gr.Data, response); err != nil {
+		return false, fmt.Errorf("failed to decode the data: %v", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,123 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeConnection drops the connection without a response
+const closeConnection = -1
+
+// newRetryServer responds the attempts with the status codes in order, and the last one for the rest attempts.
+// The OK responses are either the data or the GraphQL errors by the body.
+func newRetryServer(t *testing.T, attempts *atomic.Int32, body string, statuses ...int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := int(attempts.Add(1)) - 1
+		status := statuses[min(attempt, len(statuses)-1)]
+		switch status {
+		case closeConnection:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("failed to hijack the connection: %v", err)
+				return
+			}
+			conn.Close()
+		case http.StatusOK:
+			_, _ = w.Write([]byte(body))
+		default:
+			http.Error(w, http.StatusText(status), status)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOAPClientRetry(t *testing.T) {
+	const data = `{"data":{"result":"ok"}}`
+	tests := []struct {
+		name     string
+		body     string
+		statuses []int
+		retries  int
+		wantErr  bool
+		// wantAttempts is the number of the requests received by OAP
+		wantAttempts int32
+	}{
+		{name: "succeed at once", body: data, statuses: []int{http.StatusOK}, retries: 2, wantAttempts: 1},
+		{name: "retry 5xx", body: data, statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, retries: 2, wantAttempts: 3},
+		{name: "retry 429", body: data, statuses: []int{http.StatusTooManyRequests, http.StatusOK}, retries: 2, wantAttempts: 2},
+		{name: "retry the connection errors", body: data, statuses: []int{closeConnection, http.StatusOK}, retries: 2, wantAttempts: 2},
+		{name: "4xx is not retried", body: data, statuses: []int{http.StatusBadRequest, http.StatusOK}, retries: 2, wantErr: true, wantAttempts: 1},
+		{
+			name: "GraphQL errors are not retried", body: `{"errors":[{"message":"invalid query"}]}`,
+			statuses: []int{http.StatusOK}, retries: 2, wantErr: true, wantAttempts: 1,
+		},
+		{name: "give up after the retries", body: data, statuses: []int{http.StatusServiceUnavailable}, retries: 2, wantErr: true, wantAttempts: 3},
+		{name: "no retries", body: data, statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, wantErr: true, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := newRetryServer(t, &attempts, tt.body, tt.statuses...)
+			c := newOAPClient("oap", server.URL, server.Client(), time.Second, tt.retries)
+			c.backoff.Duration = time.Millisecond
+			var result string
+			err := c.query(context.Background(), "test", "query", nil, &struct {
+				Result *string `json:"result"`
+			}{Result: &result})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("query() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result != "ok" {
+				t.Errorf("query() = %q, want ok", result)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestOAPClientRetryCancelled(t *testing.T) {
+	var attempts atomic.Int32
+	server := newRetryServer(t, &attempts, "", http.StatusServiceUnavailable)
+	c := newOAPClient("oap", server.URL, server.Client(), time.Second, 5)
+	c.backoff.Duration, c.backoff.Cap = time.Minute, time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.query(ctx, "test", "query", nil, &struct{}{})
+	if err == nil || !strings.Contains(err.Error(), "gave up retrying") {
+		t.Errorf("query() error = %v, want giving up retrying", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("query() returns after %s", elapsed)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
@@ -20,74 +20,119 @@ package provider
 import (
 	"context"
 	"encoding/hex"
-	"flag"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	swctlapi "github.com/apache/skywalking-cli/api"
-	"github.com/apache/skywalking-cli/pkg/graphql/metrics"
-	"github.com/urfave/cli"
 	apierr "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	"k8s.io/metrics/pkg/apis/external_metrics"
 	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// The label keys to tell whether the service and destination service are normal, i.e. have agents installed
+const (
+	normalLabel     string = "normal"
+	destNormalLabel string = "dest_normal"
 )
 
 const labelValueTypeStr string = "str"
 const labelValueTypeByte string = "byte"
 const stepMinute string = "2006-01-02 1504"
 
+// metricsTypeExpression is the type of metrics defined by MQE(Metrics Query Expression)
+const metricsTypeExpression swctlapi.MetricsType = "EXPRESSION"
+
 var (
 	NsGroupResource = apischema.GroupResource{Resource: "namespaces"}
 )
 
 // externalMetricsProvider is a implementation of provider.MetricsProvider which provides metrics from OAP
 type externalMetricsProvider struct {
-	metricDefines           []*swctlapi.MetricDefinition
-	lock                    sync.RWMutex
-	ctx                     *cli.Context
-	regex                   string
-	refreshRegistryInterval time.Duration
-	namespace               string
-}
-
-type stringValue string
-
-func (sv *stringValue) String() string {
-	return string(*sv)
+	backends []*backend
+	client   dynamic.Interface
+	mapper   apimeta.RESTMapper
+	config   *config.Config
+	cache    *queryCache
+	rules    []*metricRule
+	access   *accessControl
+	// defaultOptions is the query options of the metrics without rules
+	defaultOptions queryOptions
 }
 
-func (sv *stringValue) Set(s string) error {
-	*sv = stringValue(s)
-	return nil
+// Provider serves the metrics of OAP clusters, and it's ready once the metric registries have been synced
+type Provider interface {
+	apiprovider.MetricsProvider
+	healthz.HealthChecker
 }
 
-// NewProvider returns an instance of externalMetricsProvider
-func NewProvider(baseURL string, metricRegex string, refreshRegistryInterval time.Duration, namespace string) (apiprovider.ExternalMetricsProvider, error) {
-	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
-	var k stringValue
-	if err := k.Set(baseURL); err != nil {
-		return nil, fmt.Errorf("failed to set OAP address: %v", err)
+// NewProvider returns an instance of externalMetricsProvider, which serves the metrics of the backends in the cfg.
+// The namespace is the default prefix of metric names. The client and mapper are used to resolve Kubernetes
+// objects described by custom metrics to SkyWalking entities, and the client persists the registries to a ConfigMap.
+func NewProvider(namespace string, client dynamic.Interface, mapper apimeta.RESTMapper, cfg *config.Config,
+	cacheTTL, cacheStaleTTL time.Duration, registry RegistryOptions) (Provider, error) {
+	if len(cfg.Backends) == 0 {
+		return nil, fmt.Errorf("at least one backend is required")
 	}
-	fs.Var(&k, "base-url", "")
-	ctx := cli.NewContext(nil, fs, nil)
-	if url := ctx.GlobalString("base-url"); url != k.String() {
-		return nil, fmt.Errorf("failed to set base-url: %s", url)
+	store, err := newRegistryStore(registry, client)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry store: %v", err)
 	}
 	provider := &externalMetricsProvider{
-		ctx:                     ctx,
-		regex:                   metricRegex,
-		refreshRegistryInterval: refreshRegistryInterval,
-		namespace:               namespace,
+		client: client,
+		mapper: mapper,
+		config: cfg,
+		cache:  newQueryCache(cacheTTL, cacheStaleTTL),
+	}
+	names := make(map[string]bool, len(cfg.Backends))
+	for _, bc := range cfg.Backends {
+		if names[bc.Name] {
+			return nil, fmt.Errorf("duplicated backend: %s", bc.Name)
+		}
+		names[bc.Name] = true
+		b, err := newBackend(bc, namespace, store)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend %s: %v", bc.Name, err)
+		}
+		provider.backends = append(provider.backends, b)
+	}
+	if provider.access, err = newAccessControl(cfg.Access); err != nil {
+		return nil, fmt.Errorf("invalid access configuration: %v", err)
+	}
+	if provider.defaultOptions, err = defaultQueryOptions.overlay(cfg.Query); err != nil {
+		return nil, fmt.Errorf("invalid query configuration: %v", err)
+	}
+	for _, mc := range cfg.Metrics {
+		r, err := newMetricRule(mc, provider.defaultOptions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of metric %s: %v", mc.Name, err)
+		}
+		provider.rules = append(provider.rules, r)
+	}
+	for _, e := range cfg.Expressions {
+		if e.Expression == "" {
+			return nil, fmt.Errorf("the expression of %s is required", e.Name)
+		}
+		r, err := newMetricRule(e.MetricConfig, provider.defaultOptions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of expression %s: %v", e.Name, err)
+		}
+		provider.rules = append(provider.rules, r)
+	}
+	for _, b := range provider.backends {
+		b.sync()
 	}
-	provider.sync()
 
 	return provider, nil
 }
@@ -97,6 +142,11 @@ type paramValue struct {
 	val *string
 }
 
+// empty tells whether the value is missing or empty
+func (pv *paramValue) empty() bool {
+	return pv.val == nil || *pv.val == ""
+}
+
 func (pv *paramValue) extractValue(requirements labels.Requirements) error {
 	vv := make([]string, 10)
 	for _, r := range requirements {
@@ -161,18 +211,18 @@ func bufferEntity(buff []string, index int, requirement labels.Requirement, dec
 	return buff, nil
 }
 
-func (p *externalMetricsProvider) GetExternalMetric(_ context.Context, namespace string, metricSelector labels.Selector,
-	info apiprovider.ExternalMetricInfo) (*external_metrics.ExternalMetricValueList, error) {
-	var md *swctlapi.MetricDefinition
-	for _, m := range p.metricDefines {
-		if p.getMetricNameWithNamespace(m.Name) == info.Metric {
-			md = m
-		}
-	}
+func (p *externalMetricsProvider) GetExternalMetric(ctx context.Context, namespace string, metricSelector labels.Selector,
+	info apiprovider.ExternalMetricInfo) (_ *external_metrics.ExternalMetricValueList, err error) {
+	metric := unknownMetric
+	defer func(start time.Time) {
+		observeRequest(apiExternalMetrics, metric, start, err)
+	}(time.Now())
+	b, md, rule := p.route(info.Metric, namespace)
 	if md == nil {
 		klog.Errorf("%s is missing in OAP", info.Metric)
-		return nil, apierr.NewBadRequest(fmt.Sprintf("%s is defined in OAP", info.Metric))
+		return nil, apierr.NewBadRequest(fmt.Sprintf("%s is not served to namespace %s by any OAP", info.Metric, namespace))
 	}
+	metric = info.Metric
 	requirement, selector := metricSelector.Requirements()
 	if !selector {
 		klog.Errorf("no selector for metric: %s", md.Name)
@@ -182,105 +232,381 @@ func (p *externalMetricsProvider) GetExternalMetric(_ context.Context, namespace
 	label := &paramValue{key: "label"}
 	instance := &paramValue{key: "instance"}
 	endpoint := &paramValue{key: "endpoint"}
-	extractValue(requirement, svc, label, instance, endpoint)
-	if *svc.val == "" {
+	destSvc := &paramValue{key: "dest_service"}
+	destInstance := &paramValue{key: "dest_instance"}
+	destEndpoint := &paramValue{key: "dest_endpoint"}
+	if err := extractValue(requirement, svc, label, instance, endpoint, destSvc, destInstance, destEndpoint); err != nil {
+		klog.Errorf("invalid labels of metric %s: %v", md.Name, err)
+		return nil, apierr.NewBadRequest(fmt.Sprintf("invalid labels of metric %s: %v", md.Name, err))
+	}
+	if rule != nil {
+		if err := rule.renderEntity(namespace, requirement, svc, instance, endpoint, destSvc, destInstance, destEndpoint); err != nil {
+			return nil, apierr.NewBadRequest(fmt.Sprintf("invalid entity of metric %s: %v", info.Metric, err))
+		}
+	}
+	if svc.empty() {
 		klog.Errorf("%s is lack of required label 'service'", md.Name)
 		return nil, apierr.NewBadRequest(fmt.Sprintf("%s is lack of required label 'service'", md.Name))
 	}
+	if destSvc.empty() && (!destInstance.empty() || !destEndpoint.empty()) {
+		klog.Errorf("%s is lack of required label 'dest_service'", md.Name)
+		return nil, apierr.NewBadRequest(fmt.Sprintf("%s is lack of required label 'dest_service'", md.Name))
+	}
+	normal, err := extractBool(requirement, normalLabel)
+	if err != nil {
+		return nil, apierr.NewBadRequest(err.Error())
+	}
+	destNormal, err := extractBool(requirement, destNormalLabel)
+	if err != nil {
+		return nil, apierr.NewBadRequest(err.Error())
+	}
+	opts, err := p.resolveQueryOptions(rule, requirement)
+	if err != nil {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("invalid query options of metric %s: %v", md.Name, err))
+	}
+	services := []string{*svc.val}
+	if rule == nil || rule.entity["service"] == nil {
+		if vv := extractValues(requirement, "service"); len(vv) > 1 {
+			services = vv
+		}
+	}
+	groupResource := p.selectGroupResource(namespace)
 
-	now := time.Now()
-	startTime := now.Add(-3 * time.Minute)
-	endTime := now
-	step := swctlapi.StepMinute
-	duration := swctlapi.Duration{
-		Start: startTime.Format(stepMinute),
-		End:   endTime.Format(stepMinute),
-		Step:  step,
+	items := make([]external_metrics.ExternalMetricValue, 0, len(services))
+	for i := range services {
+		entity := newEntity(&services[i], instance.val, endpoint.val)
+		setRelation(entity, destSvc.val, destInstance.val, destEndpoint.val, normal, destNormal)
+		rule.applyDefaults(entity, label)
+		if err := p.access.authorize(ctx, b, namespace, groupResource, info.Metric,
+			*entity.ServiceName, *entity.DestServiceName); err != nil {
+			return nil, err
+		}
+		metricLabels := extractValues(requirement, "label")
+		if len(metricLabels) < 2 {
+			metricLabels = nil
+			if label.val != nil && *label.val != "" {
+				metricLabels = []string{*label.val}
+			}
+		}
+
+		values, err := p.readMetricValues(ctx, b, md, entity, metricLabels, opts, groupResource, info.Metric)
+		if err != nil {
+			if len(services) > 1 && apierr.IsNotFound(err) {
+				klog.V(4).Infof("skip service %s without metric %s: %v", services[i], info.Metric, err)
+				continue
+			}
+			return nil, err
+		}
+		for _, v := range values {
+			quantity, err := newQuantity(v.value)
+			if err != nil {
+				return nil, apierr.NewInternalError(fmt.Errorf("invalid value of metric %s: %v", md.Name, err))
+			}
+			item := external_metrics.ExternalMetricValue{
+				MetricName:   info.Metric,
+				MetricLabels: map[string]string{"service": services[i]},
+				Timestamp: metav1.Time{
+					Time: v.timestamp,
+				},
+				Value: *quantity,
+			}
+			if v.label != "" {
+				item.MetricLabels["label"] = v.label
+			}
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, apiprovider.NewMetricNotFoundError(groupResource, info.Metric)
 	}
 
-	normal := true
-	empty := ""
-	entity := &swctlapi.Entity{
-		ServiceName:             svc.val,
-		ServiceInstanceName:     instance.val,
-		EndpointName:            endpoint.val,
-		Normal:                  &normal,
-		DestServiceName:         &empty,
-		DestNormal:              &normal,
-		DestServiceInstanceName: &empty,
-		DestEndpointName:        &empty,
+	return &external_metrics.ExternalMetricValueList{
+		Items: items,
+	}, nil
+}
+
+// metricValue is the value of the metric reduced from the points in the query window
+type metricValue struct {
+	label     string
+	value     float64
+	timestamp time.Time
+}
+
+// readMetricValues fetches the complete points of the metric for the entity in the query window from OAP,
+// and reduces them to a single value per label. All the labels are fetched in one query, the labels which
+// have no values are skipped. The groupResource and metricName are only used to report a missing metric.
+func (p *externalMetricsProvider) readMetricValues(ctx context.Context, b *backend, md *swctlapi.MetricDefinition, entity *swctlapi.Entity, metricLabels []string,
+	opts queryOptions, groupResource apischema.GroupResource, metricName string) ([]metricValue, error) {
+	if md.Type == swctlapi.MetricsTypeLabeledValue && len(metricLabels) == 0 {
+		klog.Errorf("%s is lack of required label 'label'", md.Name)
+		return nil, apierr.NewBadRequest(fmt.Sprintf("%s is lack of required label 'label'", md.Name))
 	}
-	entity.Scope = parseScope(entity)
+	if md.Type == swctlapi.MetricsTypeRegularValue {
+		metricLabels = nil
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s", b.name, md.Name, display(entity), strings.Join(metricLabels, ","), opts.window, opts.step)
+	cached, err := p.cache.get(ctx, key, b.oap.timeout, func(ctx context.Context) (*cachedValues, error) {
+		end := time.Now()
+		values, timeSeries, err := p.fetchMetricsValues(ctx, b, md, entity, metricLabels, opts.duration(end))
+		if err != nil {
+			return nil, err
+		}
+		return &cachedValues{values: values, timeSeries: timeSeries, end: end}, nil
+	})
+	if err != nil {
+		return nil, apierr.NewInternalError(fmt.Errorf("unable to fetch metrics: %v", err))
+	}
+
+	if len(metricLabels) == 0 {
+		values, err := selectMetricsValues(cached.values, nil, md.Name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := reduceMetricsValues(values, cached, opts, md.Name, entity)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, apiprovider.NewMetricNotFoundError(groupResource, metricName)
+		}
+		return []metricValue{*v}, nil
+	}
+	result := make([]metricValue, 0, len(metricLabels))
+	for i := range metricLabels {
+		values, err := selectMetricsValues(cached.values, &metricLabels[i], md.Name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := reduceMetricsValues(values, cached, opts, md.Name, entity)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			v.label = metricLabels[i]
+			result = append(result, *v)
+		}
+	}
+	if len(result) == 0 {
+		return nil, apiprovider.NewMetricNotFoundError(groupResource, metricName)
+	}
+	return result, nil
+}
+
+// reduceMetricsValues reduces the non-empty points of the values, it's nil if there are no complete points.
+// If the latest complete bucket of a time series is empty rather than zero, the value is served by the stale policy.
+func reduceMetricsValues(values *metricsValues, cached *cachedValues, opts queryOptions, metricName string,
+	entity *swctlapi.Entity) (*metricValue, error) {
+	if values == nil || values.Values == nil || len(values.Values.Values) < 1 {
+		return nil, nil
+	}
+	points := values.Values.Values
+	if !cached.timeSeries {
+		v, ok := opts.reduce(points)
+		if !ok {
+			return nil, nil
+		}
+		return &metricValue{value: v * opts.scale, timestamp: cached.end}, nil
+	}
+	l := len(points)
+	if l < 2 {
+		return nil, nil
+	}
+	// the last point is dropped since its bucket is not complete yet
+	points = points[:l-1]
+	step := stepDurations[opts.step]
+	latest := cached.end.Add(-step)
+
+	if points[len(points)-1].Value != nil {
+		v, _ := opts.reduce(points)
+		sValue := v * opts.scale
+		klog.V(4).Infof("metric value: %g, timestamp: %s, aggregation: %s", sValue, latest.Format(stepFormats[opts.step]), opts.aggregation)
+		return &metricValue{value: sValue, timestamp: latest}, nil
+	}
+
+	reason := staleReasonLatestEmpty
+	var v *metricValue
+	switch opts.stalePolicy {
+	case StalePolicyDefault:
+		v = &metricValue{value: opts.staleValue, timestamp: latest}
+	case StalePolicyLast:
+		for i := len(points) - 1; i >= 0; i-- {
+			if points[i].Value != nil {
+				v = &metricValue{
+					value:     max(*points[i].Value, 0) * opts.scale,
+					timestamp: latest.Add(-time.Duration(len(points)-1-i) * step),
+				}
+				break
+			}
+		}
+		if v == nil {
+			reason = staleReasonAllEmpty
+		}
+	}
+	staleValues.WithLabelValues(metricName, string(opts.stalePolicy), reason).Inc()
+	if v == nil {
+		klog.Warningf("the value of %s for %s is stale: %s, policy: %s", metricName, display(entity), reason, opts.stalePolicy)
+		return nil, apierr.NewServiceUnavailable(fmt.Sprintf("the value of %s is stale: %s", metricName, reason))
+	}
+	klog.Warningf("the value of %s for %s is stale: %s, policy: %s, serve %g at %s", metricName, display(entity), reason,
+		opts.stalePolicy, v.value, v.timestamp.Format(stepFormats[opts.step]))
+	return v, nil
+}
+
+// fetchMetricsValues queries the values of a regular metric, the values of the labels of a labeled metric,
+// or the results of an expression. It also tells whether the values are a time series.
+func (p *externalMetricsProvider) fetchMetricsValues(ctx context.Context, b *backend, md *swctlapi.MetricDefinition, entity *swctlapi.Entity,
+	metricLabels []string, duration swctlapi.Duration) ([]metricsValues, bool, error) {
 	condition := swctlapi.MetricsCondition{
 		Name:   md.Name,
 		Entity: entity,
 	}
-	var metricsValues swctlapi.MetricsValues
-	if md.Type == swctlapi.MetricsTypeRegularValue {
-		var err error
-		metricsValues, err = metrics.LinearIntValues(p.ctx, condition, duration)
+	switch md.Type {
+	case swctlapi.MetricsTypeRegularValue:
+		values, err := b.oap.linearValues(ctx, condition, duration)
 		if err != nil {
-			return nil, apierr.NewInternalError(fmt.Errorf("unable to fetch metrics: %v", err))
+			return nil, false, err
 		}
-		klog.V(4).Infof("Linear request{condition:%s, duration:%s}  response %s", display(condition), display(duration), display(metricsValues))
-	} else if md.Type == swctlapi.MetricsTypeLabeledValue {
-		if *label.val == "" {
-			klog.Errorf("%s is lack of required label 'label'", md.Name)
-			return nil, apierr.NewBadRequest(fmt.Sprintf("%s is lack of required label 'label'", md.Name))
-		}
-		result, err := metrics.MultipleLinearIntValues(p.ctx, condition, []string{*label.val}, duration)
+		klog.V(4).Infof("Linear request{condition:%s, duration:%s}  response %s", display(condition), display(duration), display(values))
+		return []metricsValues{values}, true, nil
+	case swctlapi.MetricsTypeLabeledValue:
+		result, err := b.oap.multipleLinearValues(ctx, condition, metricLabels, duration)
 		if err != nil {
-			return nil, apierr.NewInternalError(fmt.Errorf("unable to fetch metrics: %v", err))
+			return nil, false, err
 		}
 
 		klog.V(4).Infof("Labeled request{condition:%s, duration:%s, labels:%s}  response %s",
-			display(condition), display(duration), *label.val, display(result))
+			display(condition), display(duration), strings.Join(metricLabels, ","), display(result))
+		return result, true, nil
+	case metricsTypeExpression:
+		expression := p.getExpression(md.Name)
+		if expression == nil {
+			return nil, false, fmt.Errorf("expression %s is not found", md.Name)
+		}
+		resultType, result, err := b.oap.execExpression(ctx, expression.Expression, entity, duration)
+		if err != nil {
+			return nil, false, err
+		}
+
+		klog.V(4).Infof("Expression request{expression:%s, entity:%s, duration:%s}  response %s",
+			expression.Expression, display(entity), display(duration), display(result))
+		return result, resultType == expressionTypeTimeSeriesValues, nil
+	}
+	return nil, false, fmt.Errorf("unsupported type %s of metric %s", md.Type, md.Name)
+}
 
-		for _, r := range result {
-			if *r.Label == *label.val {
-				metricsValues = r
+// selectMetricsValues picks the values of the label, or the only values if the label is not specified
+func selectMetricsValues(values []metricsValues, label *string, metricName string) (*metricsValues, error) {
+	if label != nil && *label != "" {
+		for i := range values {
+			if values[i].Label != nil && *values[i].Label == *label {
+				return &values[i], nil
 			}
 		}
+		return nil, nil
 	}
-	if len(metricsValues.Values.Values) < 1 {
-		return nil, apiprovider.NewMetricNotFoundError(p.selectGroupResource(namespace), info.Metric)
+	if len(values) > 1 {
+		return nil, apierr.NewBadRequest(fmt.Sprintf("%s yields %d results, select one of them with label 'label'",
+			metricName, len(values)))
 	}
-	var sTime time.Time
-	var sValue int64
-	l := len(metricsValues.Values.Values)
-	if l < 2 {
-		return nil, apiprovider.NewMetricNotFoundError(p.selectGroupResource(namespace), info.Metric)
+	if len(values) == 1 {
+		return &values[0], nil
 	}
-	kv := metricsValues.Values.Values[l-2]
-	sTime = endTime.Add(time.Minute * time.Duration(-1))
-	if kv.Value > 0 {
-		sValue = kv.Value
+	return nil, nil
+}
+
+func newEntity(service, instance, endpoint *string) *swctlapi.Entity {
+	normal := true
+	empty := ""
+	entity := &swctlapi.Entity{
+		ServiceName:             service,
+		ServiceInstanceName:     instance,
+		EndpointName:            endpoint,
+		Normal:                  &normal,
+		DestServiceName:         &empty,
+		DestNormal:              &normal,
+		DestServiceInstanceName: &empty,
+		DestEndpointName:        &empty,
 	}
-	if sValue == 0 {
-		sTime = endTime
+	entity.Scope = parseScope(entity)
+	return entity
+}
+
+// setRelation sets the destination of the relation and whether the services are normal,
+// then infers the scope again
+func setRelation(entity *swctlapi.Entity, destService, destInstance, destEndpoint *string, normal, destNormal bool) {
+	entity.DestServiceName = destService
+	entity.DestServiceInstanceName = destInstance
+	entity.DestEndpointName = destEndpoint
+	entity.Normal = &normal
+	entity.DestNormal = &destNormal
+	entity.Scope = parseScope(entity)
+}
+
+func (p *externalMetricsProvider) getExpression(name string) *config.ExpressionConfig {
+	for i := range p.config.Expressions {
+		if p.config.Expressions[i].Name == name {
+			return &p.config.Expressions[i]
+		}
 	}
-	klog.V(4).Infof("metric value: %d, timestamp: %s", sValue, sTime.Format(stepMinute))
+	return nil
+}
 
-	return &external_metrics.ExternalMetricValueList{
-		Items: []external_metrics.ExternalMetricValue{
-			{
-				MetricName: info.Metric,
-				Timestamp: metav1.Time{
-					Time: sTime,
-				},
-				Value: *resource.NewQuantity(sValue, resource.DecimalSI),
-			},
-		},
-	}, nil
+// allMetricDefines returns the expressions and the metrics of the backend, the former take precedence if
+// the names are duplicated. The caller should hold the lock of the backend.
+func (p *externalMetricsProvider) allMetricDefines(b *backend) []*swctlapi.MetricDefinition {
+	mdd := make([]*swctlapi.MetricDefinition, 0, len(p.config.Expressions)+len(b.metricDefines))
+	expressions := make(map[string]bool, len(p.config.Expressions))
+	for _, e := range p.config.Expressions {
+		expressions[e.Name] = true
+		mdd = append(mdd, &swctlapi.MetricDefinition{Name: e.Name, Type: metricsTypeExpression})
+	}
+	for _, md := range b.metricDefines {
+		if !expressions[md.Name] {
+			mdd = append(mdd, md)
+		}
+	}
+	return mdd
 }
 
-func extractValue(requirement labels.Requirements, paramValues ...*paramValue) {
+// extractBool parses the boolean value of the key, which defaults to true
+func extractBool(requirement labels.Requirements, key string) (bool, error) {
+	for _, r := range requirement {
+		if r.Key() != key {
+			continue
+		}
+		if v, exist := r.Values().PopAny(); exist {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return false, fmt.Errorf("invalid value %s of label '%s': %v", v, key, err)
+			}
+			return b, nil
+		}
+	}
+	return true, nil
+}
+
+// extractValues returns the sorted values of the requirement on the key, e.g. `service in (a,b)`
+func extractValues(requirements labels.Requirements, key string) []string {
+	for _, r := range requirements {
+		if r.Key() != key {
+			continue
+		}
+		switch r.Operator() {
+		case selection.In, selection.Equals, selection.DoubleEquals:
+			return r.Values().List()
+		}
+	}
+	return nil
+}
+
+func extractValue(requirement labels.Requirements, paramValues ...*paramValue) error {
 	for _, pv := range paramValues {
-		err := pv.extractValue(requirement)
-		if err != nil {
-			klog.Errorf("failed to parse label %s: %v ", pv.key, err)
+		if err := pv.extractValue(requirement); err != nil {
+			return fmt.Errorf("failed to parse label %s: %v", pv.key, err)
 		}
 	}
+	return nil
 }
 
 func (p *externalMetricsProvider) selectGroupResource(namespace string) apischema.GroupResource {
@@ -294,10 +620,6 @@ func (p *externalMetricsProvider) selectGroupResource(namespace string) apischem
 	}
 }
 
-func (p *externalMetricsProvider) getMetricNameWithNamespace(metricName string) string {
-	return strings.Join([]string{p.namespace, metricName}, "|")
-}
-
 // TODO: remove this function once cli move it from internal module to pkg
 func parseScope(entity *swctlapi.Entity) swctlapi.Scope {
 	scope := swctlapi.ScopeAll
@@ -0,0 +1,501 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// newTestProvider serves the metrics of a backend by the OAP handler, and applies the rules and expressions
+// of the cfg without caching the responses
+func newTestProvider(t *testing.T, handler oapHandler, cfg *config.Config, mdd ...*swctlapi.MetricDefinition) *externalMetricsProvider {
+	t.Helper()
+	access, err := newAccessControl(cfg.Access)
+	if err != nil {
+		t.Fatalf("newAccessControl() error = %v", err)
+	}
+	p := &externalMetricsProvider{
+		backends:       []*backend{{name: "oap", oap: newFakeOAP(t, handler), metricDefines: mdd}},
+		config:         cfg,
+		cache:          newQueryCache(0, 0),
+		access:         access,
+		defaultOptions: defaultQueryOptions,
+	}
+	for _, mc := range cfg.Metrics {
+		r, err := newMetricRule(mc, p.defaultOptions)
+		if err != nil {
+			t.Fatalf("newMetricRule() error = %v", err)
+		}
+		p.rules = append(p.rules, r)
+	}
+	for _, e := range cfg.Expressions {
+		r, err := newMetricRule(e.MetricConfig, p.defaultOptions)
+		if err != nil {
+			t.Fatalf("newMetricRule() error = %v", err)
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+// series builds the values of a time series, the nil points are empty buckets
+func series(points ...*float64) *metricsValues {
+	values := &metricsValues{Values: &floatValues{}}
+	for _, p := range points {
+		values.Values.Values = append(values.Values.Values, &kvFloat{Value: p})
+	}
+	return values
+}
+
+func value(v float64) *float64 {
+	return &v
+}
+
+func TestReduceMetricsValues(t *testing.T) {
+	end := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	options := func(aggregation Aggregation, policy StalePolicy) queryOptions {
+		o := defaultQueryOptions
+		o.aggregation = aggregation
+		o.stalePolicy = policy
+		return o
+	}
+	tests := []struct {
+		name          string
+		values        *metricsValues
+		nonTimeSeries bool
+		opts          queryOptions
+		want          *metricValue
+		wantErr       func(error) bool
+	}{
+		{
+			name:   "no values",
+			values: nil,
+			opts:   defaultQueryOptions,
+		},
+		{
+			name:   "only the incomplete point",
+			values: series(value(1)),
+			opts:   defaultQueryOptions,
+		},
+		{
+			name:   "last drops the incomplete point",
+			values: series(value(1), value(2), value(3), value(100)),
+			opts:   options(AggregationLast, StalePolicyError),
+			want:   &metricValue{value: 3, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "avg skips the empty buckets",
+			values: series(value(1), nil, value(3), nil),
+			opts:   options(AggregationAvg, StalePolicyError),
+			want:   &metricValue{value: 2, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "max",
+			values: series(value(1), value(5), value(3), nil),
+			opts:   options(AggregationMax, StalePolicyError),
+			want:   &metricValue{value: 5, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "min counts the negative values as zero",
+			values: series(value(-1), value(5), value(3), nil),
+			opts:   options(AggregationMin, StalePolicyError),
+			want:   &metricValue{value: 0, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "sum is scaled",
+			values: series(value(100), value(200), value(300), nil),
+			opts: func() queryOptions {
+				o := options(AggregationSum, StalePolicyError)
+				o.scale = 0.01
+				return o
+			}(),
+			want: &metricValue{value: 6, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "a real zero is not stale",
+			values: series(value(5), value(0), nil),
+			opts:   options(AggregationLast, StalePolicyError),
+			want:   &metricValue{value: 0, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:    "stale by the error policy",
+			values:  series(value(5), nil, value(1)),
+			opts:    options(AggregationLast, StalePolicyError),
+			wantErr: apierr.IsServiceUnavailable,
+		},
+		{
+			name:   "stale by the default policy",
+			values: series(value(5), nil, value(1)),
+			opts: func() queryOptions {
+				o := options(AggregationLast, StalePolicyDefault)
+				o.staleValue = 7
+				return o
+			}(),
+			want: &metricValue{value: 7, timestamp: end.Add(-time.Minute)},
+		},
+		{
+			name:   "stale by the last policy",
+			values: series(value(5), value(0), nil, nil, value(1)),
+			opts:   options(AggregationLast, StalePolicyLast),
+			want:   &metricValue{value: 0, timestamp: end.Add(-3 * time.Minute)},
+		},
+		{
+			name:    "stale by the last policy without any value",
+			values:  series(nil, nil, value(1)),
+			opts:    options(AggregationLast, StalePolicyLast),
+			wantErr: apierr.IsServiceUnavailable,
+		},
+		{
+			name:          "non-time series",
+			values:        series(nil, value(4), value(6)),
+			nonTimeSeries: true,
+			opts:          options(AggregationSum, StalePolicyError),
+			want:          &metricValue{value: 10, timestamp: end},
+		},
+		{
+			name:          "non-time series without any value",
+			values:        series(nil, nil),
+			nonTimeSeries: true,
+			opts:          options(AggregationSum, StalePolicyError),
+		},
+	}
+	entity := &swctlapi.Entity{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached := &cachedValues{timeSeries: !tt.nonTimeSeries, end: end}
+			got, err := reduceMetricsValues(tt.values, cached, tt.opts, "service_cpm", entity)
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("reduceMetricsValues() error = %v, want a matched error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reduceMetricsValues() error = %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("reduceMetricsValues() = %+v, want nil", *got)
+				}
+				return
+			}
+			if got == nil || got.value != tt.want.value || !got.timestamp.Equal(tt.want.timestamp) {
+				t.Errorf("reduceMetricsValues() = %+v, want %+v", got, *tt.want)
+			}
+		})
+	}
+}
+
+// capturedEntity records the entities of the queries, and serves a value for each of them
+type capturedEntity struct {
+	entities []map[string]interface{}
+}
+
+func (c *capturedEntity) serve(_ string, variables map[string]interface{}) (interface{}, string) {
+	condition, _ := variables["condition"].(map[string]interface{})
+	entity, _ := condition["entity"].(map[string]interface{})
+	c.entities = append(c.entities, entity)
+	return points("", value(7), value(0)), ""
+}
+
+func TestGetExternalMetricRelation(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		// want are the fields of the entity sent to OAP
+		want    map[string]interface{}
+		wantErr func(error) bool
+	}{
+		{
+			name:     "service relation",
+			selector: "service=songs,dest_service=books",
+			want: map[string]interface{}{
+				"scope": string(swctlapi.ScopeServiceRelation), "serviceName": "songs", "destServiceName": "books",
+				"normal": true, "destNormal": true,
+			},
+		},
+		{
+			name:     "instance relation",
+			selector: "service=songs,instance=songs-0,dest_service=books,dest_instance=books-0",
+			want: map[string]interface{}{
+				"scope": string(swctlapi.ScopeServiceInstanceRelation), "serviceInstanceName": "songs-0",
+				"destServiceName": "books", "destServiceInstanceName": "books-0",
+			},
+		},
+		{
+			name:     "endpoint relation",
+			selector: "service=songs,endpoint=list-songs,dest_service=books,dest_endpoint=list-books",
+			want: map[string]interface{}{
+				"scope": string(swctlapi.ScopeEndpointRelation), "endpointName": "list-songs",
+				"destServiceName": "books", "destEndpointName": "list-books",
+			},
+		},
+		{
+			name:     "the destination is not a normal service",
+			selector: "service=songs,dest_service=mysql,dest_normal=false",
+			want: map[string]interface{}{
+				"scope": string(swctlapi.ScopeServiceRelation), "normal": true, "destNormal": false,
+			},
+		},
+		{
+			name:     "the destination instance requires the destination service",
+			selector: "service=songs,dest_instance=books-0",
+			wantErr:  apierr.IsBadRequest,
+		},
+		{
+			name:     "the normal label is boolean",
+			selector: "service=songs,dest_service=books,dest_normal=maybe",
+			wantErr:  apierr.IsBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := &capturedEntity{}
+			p := newTestProvider(t, captured.serve, &config.Config{},
+				&swctlapi.MetricDefinition{Name: "service_relation_server_cpm", Type: swctlapi.MetricsTypeRegularValue})
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			got, err := p.GetExternalMetric(context.Background(), "music", selector,
+				apiprovider.ExternalMetricInfo{Metric: "service_relation_server_cpm"})
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("GetExternalMetric() error = %v", err)
+				}
+				if len(captured.entities) > 0 {
+					t.Errorf("OAP is queried with the invalid entity %v", captured.entities[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetExternalMetric() error = %v", err)
+			}
+			if len(got.Items) != 1 || got.Items[0].Value.Value() != 7 {
+				t.Errorf("GetExternalMetric() = %v", got.Items)
+			}
+			if len(captured.entities) != 1 {
+				t.Fatalf("OAP is queried %d times, want 1", len(captured.entities))
+			}
+			for field, want := range tt.want {
+				if got := captured.entities[0][field]; got != want {
+					t.Errorf("%s = %v, want %v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetExternalMetricMultipleValues(t *testing.T) {
+	// the values of the services, the labels of the labeled metric are valued by their indexes on top
+	services := map[string]float64{"songs": 10, "books": 20}
+	labelValues := map[string]float64{"p50": 1, "p99": 2}
+	var queries int
+	handler := func(_ string, variables map[string]interface{}) (interface{}, string) {
+		queries++
+		condition, _ := variables["condition"].(map[string]interface{})
+		entity, _ := condition["entity"].(map[string]interface{})
+		base, exist := services[entity["serviceName"].(string)]
+		requested, labeled := variables["labels"].([]interface{})
+		if !labeled {
+			if !exist {
+				return points(""), ""
+			}
+			return points("", value(base), value(0)), ""
+		}
+		result := []interface{}{}
+		for _, l := range requested {
+			if v, found := labelValues[l.(string)]; found && exist {
+				result = append(result, points(l.(string), value(base+v), value(0)))
+			}
+		}
+		return result, ""
+	}
+	tests := []struct {
+		name     string
+		metric   string
+		selector string
+		// want are the values by the service and label joined with `/`
+		want        map[string]int64
+		wantQueries int
+		wantErr     func(error) bool
+	}{
+		{
+			name: "the labels of a service", metric: "service_percentile", selector: "service=songs,label in (p50,p99)",
+			want: map[string]int64{"songs/p50": 11, "songs/p99": 12}, wantQueries: 1,
+		},
+		{
+			name: "the labels without values are skipped", metric: "service_percentile", selector: "service=songs,label in (p50,p95)",
+			want: map[string]int64{"songs/p50": 11}, wantQueries: 1,
+		},
+		{
+			name: "the services", metric: "service_cpm", selector: "service in (songs,books)",
+			want: map[string]int64{"songs/": 10, "books/": 20}, wantQueries: 2,
+		},
+		{
+			name: "the services without values are skipped", metric: "service_cpm", selector: "service in (songs,books,movies)",
+			want: map[string]int64{"songs/": 10, "books/": 20}, wantQueries: 3,
+		},
+		{
+			name: "the labels of the services", metric: "service_percentile", selector: "service in (songs,books),label in (p50,p99)",
+			want:        map[string]int64{"songs/p50": 11, "songs/p99": 12, "books/p50": 21, "books/p99": 22},
+			wantQueries: 2,
+		},
+		{
+			name: "none of the services has values", metric: "service_cpm", selector: "service in (movies,games)",
+			wantQueries: 2, wantErr: apierr.IsNotFound,
+		},
+		{
+			name: "the label of the labeled metric is required", metric: "service_percentile", selector: "service=songs",
+			wantErr: apierr.IsBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries = 0
+			p := newTestProvider(t, handler, &config.Config{},
+				&swctlapi.MetricDefinition{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue},
+				&swctlapi.MetricDefinition{Name: "service_percentile", Type: swctlapi.MetricsTypeLabeledValue})
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			got, err := p.GetExternalMetric(context.Background(), "music", selector, apiprovider.ExternalMetricInfo{Metric: tt.metric})
+			if queries != tt.wantQueries {
+				t.Errorf("OAP is queried %d times, want %d", queries, tt.wantQueries)
+			}
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("GetExternalMetric() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetExternalMetric() error = %v", err)
+			}
+			values := make(map[string]int64, len(got.Items))
+			for _, item := range got.Items {
+				values[item.MetricLabels["service"]+"/"+item.MetricLabels["label"]] = item.Value.Value()
+			}
+			if len(values) != len(tt.want) || len(got.Items) != len(tt.want) {
+				t.Fatalf("GetExternalMetric() = %v, want %v", values, tt.want)
+			}
+			for key, want := range tt.want {
+				if v, found := values[key]; !found || v != want {
+					t.Errorf("%s = %d, want %d", key, v, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetExternalMetricExpression(t *testing.T) {
+	cfg := &config.Config{Expressions: []config.ExpressionConfig{
+		{MetricConfig: config.MetricConfig{Name: "service_error_rate"}, Expression: "100 - service_sla / 100"},
+		{MetricConfig: config.MetricConfig{Name: "service_cpm"}, Expression: "service_cpm * 2"},
+		{MetricConfig: config.MetricConfig{Name: "service_apdex_now"}, Expression: "latest(service_apdex)"},
+		{
+			MetricConfig: config.MetricConfig{Name: "slowest_endpoint", QueryConfig: config.QueryConfig{Aggregation: "max"}},
+			Expression:   "top_n(endpoint_resp_time, 2, des)",
+		},
+		{MetricConfig: config.MetricConfig{Name: "service_percentile_of"}, Expression: "service_percentile{p='50,99'}"},
+		{MetricConfig: config.MetricConfig{Name: "broken"}, Expression: "missing_metric"},
+	}}
+	results := map[string]map[string]interface{}{
+		"100 - service_sla / 100": {
+			"type": expressionTypeTimeSeriesValues, "results": []interface{}{mqeResult(nil, ptrTo("5"), ptrTo("1"))},
+		},
+		"service_cpm * 2": {
+			"type": expressionTypeTimeSeriesValues, "results": []interface{}{mqeResult(nil, ptrTo("8"), ptrTo("0"))},
+		},
+		"latest(service_apdex)": {
+			"type": expressionTypeSingleValue, "results": []interface{}{mqeResult(nil, ptrTo("3"))},
+		},
+		"top_n(endpoint_resp_time, 2, des)": {
+			"type": expressionTypeSortedList, "results": []interface{}{mqeResult(nil, ptrTo("9"), ptrTo("4"))},
+		},
+		"service_percentile{p='50,99'}": {
+			"type": expressionTypeTimeSeriesValues, "results": []interface{}{
+				mqeResult([]string{"50"}, ptrTo("100"), ptrTo("0")),
+				mqeResult([]string{"99"}, ptrTo("900"), ptrTo("0")),
+			},
+		},
+		"missing_metric": {"type": "UNKNOWN", "error": "Metric: [missing_metric] dose not exist."},
+	}
+	handler := func(query string, variables map[string]interface{}) (interface{}, string) {
+		if !strings.Contains(query, "execExpression") {
+			return nil, "unexpected query " + query
+		}
+		return results[variables["expression"].(string)], ""
+	}
+	p := newTestProvider(t, handler, cfg,
+		&swctlapi.MetricDefinition{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue})
+	tests := []struct {
+		name     string
+		metric   string
+		selector string
+		want     int64
+		wantErr  func(error) bool
+	}{
+		{name: "the last point of the time series is dropped", metric: "service_error_rate", selector: "service=songs", want: 5},
+		{name: "the expression takes precedence over the metric", metric: "service_cpm", selector: "service=songs", want: 8},
+		{name: "the single value is kept", metric: "service_apdex_now", selector: "service=songs", want: 3},
+		{name: "the sorted list is reduced by the aggregation", metric: "slowest_endpoint", selector: "service=songs", want: 9},
+		{name: "a result is picked by the label", metric: "service_percentile_of", selector: "service=songs,label=99", want: 900},
+		{
+			name: "multiple results require the label", metric: "service_percentile_of", selector: "service=songs",
+			wantErr: apierr.IsBadRequest,
+		},
+		{
+			name: "the label matches none of the results", metric: "service_percentile_of", selector: "service=songs,label=95",
+			wantErr: apierr.IsNotFound,
+		},
+		{name: "the error of the expression", metric: "broken", selector: "service=songs", wantErr: apierr.IsInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			got, err := p.GetExternalMetric(context.Background(), "music", selector, apiprovider.ExternalMetricInfo{Metric: tt.metric})
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("GetExternalMetric() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetExternalMetric() error = %v", err)
+			}
+			if len(got.Items) != 1 || got.Items[0].Value.Value() != tt.want {
+				t.Errorf("GetExternalMetric() = %v, want %d", got.Items, tt.want)
+			}
+		})
+	}
+}
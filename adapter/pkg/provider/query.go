@@ -0,0 +1,216 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// The reserved label keys to override the query options of a HPA
+const (
+	windowLabel      string = "window"
+	stepLabel        string = "step"
+	aggregationLabel string = "aggregation"
+	stalePolicyLabel string = "stale_policy"
+)
+
+// Aggregation reduces the values in the query window to a single value
+type Aggregation string
+
+const (
+	AggregationLast Aggregation = "last"
+	AggregationAvg  Aggregation = "avg"
+	AggregationMax  Aggregation = "max"
+	AggregationMin  Aggregation = "min"
+	AggregationSum  Aggregation = "sum"
+)
+
+// StalePolicy tells how to serve a metric whose latest complete bucket is empty,
+// which is usually caused by an OAP outage or a gap of agents
+type StalePolicy string
+
+const (
+	// StalePolicyError returns an error, so that the HPA holds the current scale
+	StalePolicyError StalePolicy = "error"
+	// StalePolicyLast returns the latest non-empty bucket with its own timestamp
+	StalePolicyLast StalePolicy = "last"
+	// StalePolicyDefault returns the configured default value
+	StalePolicyDefault StalePolicy = "default"
+)
+
+// The reasons why a value is stale
+const (
+	staleReasonLatestEmpty string = "latest_bucket_empty"
+	staleReasonAllEmpty    string = "all_buckets_empty"
+)
+
+var defaultQueryOptions = queryOptions{
+	window:      3 * time.Minute,
+	step:        swctlapi.StepMinute,
+	aggregation: AggregationLast,
+	scale:       1,
+	stalePolicy: StalePolicyError,
+}
+
+var stepDurations = map[swctlapi.Step]time.Duration{
+	swctlapi.StepSecond: time.Second,
+	swctlapi.StepMinute: time.Minute,
+	swctlapi.StepHour:   time.Hour,
+	swctlapi.StepDay:    24 * time.Hour,
+}
+
+var stepFormats = map[swctlapi.Step]string{
+	swctlapi.StepSecond: "2006-01-02 150405",
+	swctlapi.StepMinute: stepMinute,
+	swctlapi.StepHour:   "2006-01-02 15",
+	swctlapi.StepDay:    "2006-01-02",
+}
+
+// queryOptions is the resolved options to query and reduce metric values
+type queryOptions struct {
+	window      time.Duration
+	step        swctlapi.Step
+	aggregation Aggregation
+	// scale is multiplied to the reduced value
+	scale       float64
+	stalePolicy StalePolicy
+	// staleValue is served by StalePolicyDefault
+	staleValue float64
+}
+
+// overlay replaces the options with non-empty fields of the QueryConfig
+func (o queryOptions) overlay(qc config.QueryConfig) (queryOptions, error) {
+	if qc.Window != 0 {
+		o.window = qc.Window
+	}
+	if qc.Step != "" {
+		step := swctlapi.Step(strings.ToUpper(qc.Step))
+		if _, ok := stepDurations[step]; !ok {
+			return o, fmt.Errorf("invalid step: %s", qc.Step)
+		}
+		o.step = step
+	}
+	if qc.Aggregation != "" {
+		aggregation := Aggregation(strings.ToLower(qc.Aggregation))
+		switch aggregation {
+		case AggregationLast, AggregationAvg, AggregationMax, AggregationMin, AggregationSum:
+		default:
+			return o, fmt.Errorf("invalid aggregation: %s", qc.Aggregation)
+		}
+		o.aggregation = aggregation
+	}
+	if qc.StalePolicy != "" {
+		policy := StalePolicy(strings.ToLower(qc.StalePolicy))
+		switch policy {
+		case StalePolicyError, StalePolicyLast, StalePolicyDefault:
+		default:
+			return o, fmt.Errorf("invalid stale policy: %s", qc.StalePolicy)
+		}
+		o.stalePolicy = policy
+	}
+	if qc.StaleValue != nil {
+		o.staleValue = *qc.StaleValue
+	}
+	if o.window < stepDurations[o.step] {
+		return o, fmt.Errorf("window %s is shorter than step %s", o.window, o.step)
+	}
+	return o, nil
+}
+
+// duration returns the time range ends at the time
+func (o queryOptions) duration(end time.Time) swctlapi.Duration {
+	format := stepFormats[o.step]
+	return swctlapi.Duration{
+		Start: end.Add(-o.window).Format(format),
+		End:   end.Format(format),
+		Step:  o.step,
+	}
+}
+
+// reduce aggregates the non-empty values to a single one, the negative values are counted as zero.
+// It's false if all the values are empty.
+func (o queryOptions) reduce(values []*kvFloat) (float64, bool) {
+	var result float64
+	var n int
+	for _, kv := range values {
+		if kv.Value == nil {
+			continue
+		}
+		v := max(*kv.Value, 0)
+		switch o.aggregation {
+		case AggregationLast:
+			result = v
+		case AggregationAvg, AggregationSum:
+			result += v
+		case AggregationMax:
+			if n == 0 || v > result {
+				result = v
+			}
+		case AggregationMin:
+			if n == 0 || v < result {
+				result = v
+			}
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	if o.aggregation == AggregationAvg {
+		result /= float64(n)
+	}
+	return result, true
+}
+
+// resolveQueryOptions overlays the query options of the rule with the reserved labels of the selector.
+// The default options are used if the rule is nil.
+func (p *externalMetricsProvider) resolveQueryOptions(rule *metricRule, requirements labels.Requirements) (queryOptions, error) {
+	o := p.defaultOptions
+	if rule != nil {
+		o = rule.options
+	}
+
+	var qc config.QueryConfig
+	var err error
+	for _, r := range requirements {
+		v, exist := r.Values().PopAny()
+		if !exist {
+			continue
+		}
+		switch r.Key() {
+		case windowLabel:
+			if qc.Window, err = time.ParseDuration(v); err != nil {
+				return o, fmt.Errorf("invalid window: %v", err)
+			}
+		case stepLabel:
+			qc.Step = v
+		case aggregationLabel:
+			qc.Aggregation = v
+		case stalePolicyLabel:
+			qc.StalePolicy = v
+		}
+	}
+	return o.overlay(qc)
+}
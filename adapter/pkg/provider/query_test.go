@@ -0,0 +1,107 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+func TestResolveQueryOptions(t *testing.T) {
+	p := &externalMetricsProvider{defaultOptions: defaultQueryOptions}
+	rule, err := newMetricRule(config.MetricConfig{
+		Name:        "service_percentile",
+		QueryConfig: config.QueryConfig{Window: 10 * time.Minute, Aggregation: "max"},
+		Scale:       0.01,
+	}, defaultQueryOptions)
+	if err != nil {
+		t.Fatalf("newMetricRule() error = %v", err)
+	}
+	withDefaults := func(modify func(o *queryOptions)) queryOptions {
+		o := defaultQueryOptions
+		modify(&o)
+		return o
+	}
+	tests := []struct {
+		name     string
+		rule     *metricRule
+		selector string
+		want     queryOptions
+		wantErr  bool
+	}{
+		{
+			name: "the defaults without rule",
+			want: defaultQueryOptions,
+		},
+		{
+			name: "the options of the rule",
+			rule: rule,
+			want: withDefaults(func(o *queryOptions) {
+				o.window = 10 * time.Minute
+				o.aggregation = AggregationMax
+				o.scale = 0.01
+			}),
+		},
+		{
+			name:     "the labels overlay the defaults",
+			selector: "window=1h,step=hour,aggregation=AVG,stale_policy=last,service=agent",
+			want: withDefaults(func(o *queryOptions) {
+				o.window = time.Hour
+				o.step = swctlapi.StepHour
+				o.aggregation = AggregationAvg
+				o.stalePolicy = StalePolicyLast
+			}),
+		},
+		{
+			name:     "the labels overlay the rule",
+			rule:     rule,
+			selector: "aggregation=min",
+			want: withDefaults(func(o *queryOptions) {
+				o.window = 10 * time.Minute
+				o.aggregation = AggregationMin
+				o.scale = 0.01
+			}),
+		},
+		{name: "invalid window", selector: "window=1x", wantErr: true},
+		{name: "invalid step", selector: "step=week", wantErr: true},
+		{name: "invalid aggregation", selector: "aggregation=median", wantErr: true},
+		{name: "invalid stale policy", selector: "stale_policy=zero", wantErr: true},
+		{name: "window shorter than step", selector: "window=30m,step=hour", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			requirements, _ := selector.Requirements()
+			got, err := p.resolveQueryOptions(tt.rule, requirements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveQueryOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("resolveQueryOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
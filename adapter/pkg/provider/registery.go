@@ -19,44 +19,50 @@ package provider
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http"
 
-	"github.com/apache/skywalking-cli/pkg/graphql/metrics"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/klog/v2"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
 	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
 )
 
 func (p *externalMetricsProvider) ListAllExternalMetrics() (externalMetricsInfo []apiprovider.ExternalMetricInfo) {
-	p.lock.RLock()
-	defer p.lock.RUnlock()
-
-	for _, md := range p.metricDefines {
+	for _, name := range p.metricNames() {
 		info := apiprovider.ExternalMetricInfo{
-			Metric: p.getMetricNameWithNamespace(md.Name),
+			Metric: name,
 		}
 		externalMetricsInfo = append(externalMetricsInfo, info)
 	}
 	return
 }
 
-func (p *externalMetricsProvider) sync() {
-	go wait.Until(func() {
-		if err := p.updateMetrics(); err != nil {
-			klog.Errorf("failed to update metrics: %v", err)
+func (p *externalMetricsProvider) ListAllMetrics() (customMetricsInfo []apiprovider.CustomMetricInfo) {
+	groupResources := append([]apischema.GroupResource{PodGroupResource}, WorkloadGroupResources...)
+	for _, name := range p.metricNames() {
+		for _, gr := range groupResources {
+			info := apiprovider.CustomMetricInfo{
+				GroupResource: gr,
+				Namespaced:    true,
+				Metric:        name,
+			}
+			customMetricsInfo = append(customMetricsInfo, info)
 		}
-	}, p.refreshRegistryInterval, wait.NeverStop)
+	}
+	return
 }
 
-func (p *externalMetricsProvider) updateMetrics() error {
-	mdd, err := metrics.ListMetrics(p.ctx, p.regex)
-	if err != nil {
-		return err
-	}
-	klog.Infof("Get service metrics: %s", display(mdd))
-	if len(mdd) > 0 {
-		p.lock.Lock()
-		defer p.lock.Unlock()
-		p.metricDefines = mdd
+// Name is the name of the readiness check
+func (p *externalMetricsProvider) Name() string {
+	return "metric-registry"
+}
+
+// Check fails until the metric registries of all backends have been synced with OAP or restored from the store,
+// so that the HPAs don't see missing metrics right after the adapter restarts
+func (p *externalMetricsProvider) Check(_ *http.Request) error {
+	for _, b := range p.backends {
+		if !b.ready.Load() {
+			return fmt.Errorf("the metric registry of backend %s has not been synced with OAP", b.name)
+		}
 	}
 	return nil
 }
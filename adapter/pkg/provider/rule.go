@@ -0,0 +1,217 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// metricRule is the parsed config.MetricConfig
+type metricRule struct {
+	config.MetricConfig
+	// options is the query options before overlaid by the selector
+	options queryOptions
+	// entity are the templates of entity fields keyed by the label keys
+	entity map[string]*template.Template
+}
+
+// entityTemplateData is the data to render the entity templates
+type entityTemplateData struct {
+	// Namespace is the namespace of the HPA
+	Namespace string
+	// Labels are the selector labels of the metric
+	Labels map[string]string
+}
+
+func newMetricRule(mc config.MetricConfig, base queryOptions) (*metricRule, error) {
+	if mc.Name == "" {
+		return nil, fmt.Errorf("the name of metric is required")
+	}
+	r := &metricRule{MetricConfig: mc}
+	var err error
+	if r.options, err = base.overlay(mc.QueryConfig); err != nil {
+		return nil, err
+	}
+	if mc.Scale != 0 {
+		r.options.scale = mc.Scale
+	}
+	if mc.Scope != "" && !swctlapi.Scope(mc.Scope).IsValid() {
+		return nil, fmt.Errorf("invalid scope: %s", mc.Scope)
+	}
+	r.entity = make(map[string]*template.Template)
+	for key, text := range map[string]string{
+		"service":       mc.Entity.Service,
+		"instance":      mc.Entity.Instance,
+		"endpoint":      mc.Entity.Endpoint,
+		"dest_service":  mc.Entity.DestService,
+		"dest_instance": mc.Entity.DestInstance,
+		"dest_endpoint": mc.Entity.DestEndpoint,
+	} {
+		if text == "" {
+			continue
+		}
+		if r.entity[key], err = template.New(key).Option("missingkey=zero").Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %v", key, err)
+		}
+	}
+	return r, nil
+}
+
+// renderEntity overrides the entity fields by the templates of the rule
+func (r *metricRule) renderEntity(namespace string, requirements labels.Requirements, fields ...*paramValue) error {
+	data := entityTemplateData{
+		Namespace: namespace,
+		Labels:    make(map[string]string, len(requirements)),
+	}
+	for _, req := range requirements {
+		if v, exist := req.Values().PopAny(); exist {
+			data.Labels[req.Key()] = v
+		}
+	}
+	for _, f := range fields {
+		t := r.entity[f.key]
+		if t == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render %s: %v", f.key, err)
+		}
+		val := buf.String()
+		f.val = &val
+	}
+	return nil
+}
+
+// applyDefaults sets the default label and scope of the rule
+func (r *metricRule) applyDefaults(entity *swctlapi.Entity, label *paramValue) {
+	if r == nil {
+		return
+	}
+	if r.Scope != "" {
+		entity.Scope = swctlapi.Scope(r.Scope)
+	}
+	if r.Label != "" && (label.val == nil || *label.val == "") {
+		l := r.Label
+		label.val = &l
+	}
+}
+
+// metricName returns the name exposed to Kubernetes of the rule for the backend
+func (r *metricRule) metricName(b *backend) string {
+	if r.As != "" {
+		return r.As
+	}
+	return b.metricName(r.Name)
+}
+
+// route picks the backend serving the metric to the Kubernetes namespace, and finds the metric and its rule
+// by the name exposed to Kubernetes. The backends listing the namespace take precedence over the ones
+// serving all namespaces. The metric is nil if no backend serves it.
+func (p *externalMetricsProvider) route(metricName, namespace string) (*backend, *swctlapi.MetricDefinition, *metricRule) {
+	var fallback *backend
+	var fallbackMetric *swctlapi.MetricDefinition
+	var fallbackRule *metricRule
+	for _, b := range p.backends {
+		serves, explicit := b.serves(namespace)
+		if !serves {
+			continue
+		}
+		md, rule := p.resolveMetric(b, metricName)
+		if md == nil {
+			continue
+		}
+		if explicit {
+			return b, md, rule
+		}
+		if fallback == nil {
+			fallback, fallbackMetric, fallbackRule = b, md, rule
+		}
+	}
+	return fallback, fallbackMetric, fallbackRule
+}
+
+// resolveMetric finds the metric of the backend and its rule by the name exposed to Kubernetes, the rule is nil
+// if there is no rule for the metric.
+func (p *externalMetricsProvider) resolveMetric(b *backend, metricName string) (*swctlapi.MetricDefinition, *metricRule) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	mdd := p.allMetricDefines(b)
+	find := func(name string) *swctlapi.MetricDefinition {
+		for _, md := range mdd {
+			if md.Name == name {
+				return md
+			}
+		}
+		return nil
+	}
+	for _, r := range p.rules {
+		if r.As != "" && r.As == metricName {
+			if md := find(r.Name); md != nil {
+				return md, r
+			}
+		}
+	}
+	for _, md := range mdd {
+		if b.metricName(md.Name) == metricName {
+			for _, r := range p.rules {
+				if r.As == "" && r.Name == md.Name {
+					return md, r
+				}
+			}
+			return md, nil
+		}
+	}
+	return nil, nil
+}
+
+// metricNames returns all the names exposed to Kubernetes of the backends without duplicates
+func (p *externalMetricsProvider) metricNames() []string {
+	var names []string
+	exist := make(map[string]bool)
+	add := func(name string) {
+		if !exist[name] {
+			exist[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, b := range p.backends {
+		b.lock.RLock()
+		mdd := p.allMetricDefines(b)
+		b.lock.RUnlock()
+		defined := make(map[string]bool, len(mdd))
+		for _, md := range mdd {
+			defined[md.Name] = true
+			add(b.metricName(md.Name))
+		}
+		for _, r := range p.rules {
+			if r.As != "" && defined[r.Name] {
+				add(r.As)
+			}
+		}
+	}
+	return names
+}
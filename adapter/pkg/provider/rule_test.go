@@ -0,0 +1,266 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	apiprovider "sigs.k8s.io/custom-metrics-apiserver/pkg/provider"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+func TestRenderEntity(t *testing.T) {
+	tests := []struct {
+		name     string
+		entity   config.EntityConfig
+		selector string
+		// want are the values of service and instance after rendered, nil if it's untouched
+		want    []*string
+		wantErr bool
+	}{
+		{
+			name:     "render from the labels and namespace",
+			entity:   config.EntityConfig{Service: "{{ .Labels.app }}|{{ .Namespace }}", Instance: "{{ .Labels.pod }}"},
+			selector: "app=songs,pod=songs-0",
+			want:     []*string{ptrTo("songs|skywalking"), ptrTo("songs-0")},
+		},
+		{
+			name:     "the missing labels are empty",
+			entity:   config.EntityConfig{Service: "{{ .Labels.app }}|{{ .Namespace }}"},
+			selector: "pod=songs-0",
+			want:     []*string{ptrTo("|skywalking"), nil},
+		},
+		{
+			name:     "the fields without templates are kept",
+			selector: "service=songs",
+			want:     []*string{nil, nil},
+		},
+		{
+			name:     "failed to render",
+			entity:   config.EntityConfig{Service: "{{ .Labels.app.name }}"},
+			selector: "app=songs",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newMetricRule(config.MetricConfig{Name: "service_cpm", Entity: tt.entity}, defaultQueryOptions)
+			if err != nil {
+				t.Fatalf("newMetricRule() error = %v", err)
+			}
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			requirements, _ := selector.Requirements()
+			svc := &paramValue{key: "service"}
+			instance := &paramValue{key: "instance"}
+			err = rule.renderEntity("skywalking", requirements, svc, instance)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderEntity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, pv := range []*paramValue{svc, instance} {
+				want := tt.want[i]
+				if (pv.val == nil) != (want == nil) || (want != nil && *pv.val != *want) {
+					t.Errorf("%s = %v, want %v", pv.key, display(pv.val), display(want))
+				}
+			}
+		})
+	}
+}
+
+func TestRenderObjectEntity(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "songs-0",
+			"namespace":   "music",
+			"annotations": map[string]interface{}{serviceNameAnnotation: "songs"},
+		},
+	}}
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "songs", "namespace": "music"},
+	}}
+	deployments := apischema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name     string
+		entity   config.EntityConfig
+		obj      *unstructured.Unstructured
+		resource apischema.GroupResource
+		selector string
+		// want are the service, instance and endpoint of the entity
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:     "the pod is mapped without templates",
+			obj:      pod,
+			resource: PodGroupResource,
+			want:     []string{"songs", "songs-0", ""},
+		},
+		{
+			name:     "the templates of the pod",
+			entity:   config.EntityConfig{Service: "v1|{{ .Labels.app }}|{{ .Namespace }}", Instance: "{{ .Labels.app }}@{{ .Namespace }}"},
+			obj:      pod,
+			resource: PodGroupResource,
+			selector: "app=songs",
+			want:     []string{"v1|songs|music", "songs@music", ""},
+		},
+		{
+			name:     "the templates of the workload",
+			entity:   config.EntityConfig{Service: "v1|{{ .Labels.app }}|{{ .Namespace }}", Endpoint: "GET:/{{ .Labels.app }}"},
+			obj:      deployment,
+			resource: deployments,
+			selector: "app=songs",
+			want:     []string{"v1|songs|music", "", "GET:/songs"},
+		},
+		{
+			name:     "failed to render",
+			entity:   config.EntityConfig{Service: "{{ .Labels.app.name }}"},
+			obj:      deployment,
+			resource: deployments,
+			selector: "app=songs",
+			wantErr:  true,
+		},
+	}
+	p := &externalMetricsProvider{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newMetricRule(config.MetricConfig{Name: "service_instance_cpm", Entity: tt.entity}, defaultQueryOptions)
+			if err != nil {
+				t.Fatalf("newMetricRule() error = %v", err)
+			}
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("labels.Parse(%q) error = %v", tt.selector, err)
+			}
+			requirements, _ := selector.Requirements()
+			info := apiprovider.CustomMetricInfo{GroupResource: tt.resource, Namespaced: true, Metric: "service_instance_cpm"}
+			entity, _, err := p.objectEntity(tt.obj, rule, info, requirements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("objectEntity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if entity == nil {
+				t.Fatal("objectEntity() = nil")
+			}
+			for i, got := range []*string{entity.ServiceName, entity.ServiceInstanceName, entity.EndpointName} {
+				if got == nil || *got != tt.want[i] {
+					t.Errorf("field %d = %v, want %s", i, display(got), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// newRouteBackend serves the regular metrics to the namespaces, or all namespaces if there are none
+func newRouteBackend(name string, metrics []string, namespaces ...string) *backend {
+	b := &backend{name: name, kubeNamespaces: make(map[string]bool, len(namespaces))}
+	for _, ns := range namespaces {
+		b.kubeNamespaces[ns] = true
+	}
+	for _, m := range metrics {
+		b.metricDefines = append(b.metricDefines, &swctlapi.MetricDefinition{Name: m, Type: swctlapi.MetricsTypeRegularValue})
+	}
+	return b
+}
+
+func TestRoute(t *testing.T) {
+	rule, err := newMetricRule(config.MetricConfig{Name: "service_cpm", As: "songs-cpm"}, defaultQueryOptions)
+	if err != nil {
+		t.Fatalf("newMetricRule() error = %v", err)
+	}
+	p := &externalMetricsProvider{
+		config: &config.Config{},
+		rules:  []*metricRule{rule},
+		backends: []*backend{
+			newRouteBackend("all-1", []string{"service_cpm", "shared_cpm"}),
+			newRouteBackend("all-2", []string{"service_cpm", "shared_cpm", "all2_cpm"}),
+			newRouteBackend("music", []string{"service_cpm"}, "music"),
+			newRouteBackend("books", []string{"books_cpm"}, "books", "library"),
+		},
+	}
+	tests := []struct {
+		name      string
+		metric    string
+		namespace string
+		// want is the name of the backend, no backend serves the metric if it's empty
+		want     string
+		wantRule bool
+	}{
+		{name: "the backend listing the namespace takes precedence", metric: "service_cpm", namespace: "music", want: "music"},
+		{name: "the first backend serving all namespaces", metric: "service_cpm", namespace: "books", want: "all-1"},
+		{name: "the backend listing the namespace lacks the metric", metric: "shared_cpm", namespace: "music", want: "all-1"},
+		{name: "the only backend of the metric", metric: "all2_cpm", namespace: "music", want: "all-2"},
+		{name: "any namespace listed", metric: "books_cpm", namespace: "library", want: "books"},
+		{name: "the namespace isn't listed", metric: "books_cpm", namespace: "music"},
+		{name: "the metric is missing", metric: "missing_cpm", namespace: "music"},
+		{name: "the alias of the rule", metric: "songs-cpm", namespace: "music", want: "music", wantRule: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, md, r := p.route(tt.metric, tt.namespace)
+			if tt.want == "" {
+				if b != nil || md != nil || r != nil {
+					t.Fatalf("route() = %v, want nil", b)
+				}
+				return
+			}
+			if b == nil || md == nil {
+				t.Fatalf("route() = nil, want %s", tt.want)
+			}
+			if b.name != tt.want {
+				t.Errorf("route() = %s, want %s", b.name, tt.want)
+			}
+			if (r != nil) != tt.wantRule {
+				t.Errorf("route() rule = %v, want rule %t", r, tt.wantRule)
+			}
+		})
+	}
+
+	t.Run("no backend serves the external metric", func(t *testing.T) {
+		_, err := p.GetExternalMetric(context.Background(), "music", labels.Everything(),
+			apiprovider.ExternalMetricInfo{Metric: "books_cpm"})
+		if !apierr.IsBadRequest(err) || !strings.Contains(err.Error(), "books_cpm is not served to namespace music") {
+			t.Errorf("GetExternalMetric() error = %v", err)
+		}
+	})
+	t.Run("no backend serves the custom metric", func(t *testing.T) {
+		info := apiprovider.CustomMetricInfo{GroupResource: PodGroupResource, Namespaced: true, Metric: "books_cpm"}
+		_, err := p.GetMetricBySelector(context.Background(), "music", labels.Everything(), info, labels.Everything())
+		if !apierr.IsNotFound(err) {
+			t.Errorf("GetMetricBySelector() error = %v", err)
+		}
+	})
+}
+
+func ptrTo(s string) *string {
+	return &s
+}
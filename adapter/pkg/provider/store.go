@@ -0,0 +1,185 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// RegistryOptions is where to persist the metric registries, so that they are served on boot even if OAP is down.
+// At most one of them could be set.
+type RegistryOptions struct {
+	// File is the path of a local file
+	File string
+	// ConfigMap is the ConfigMap in the form of `namespace/name`
+	ConfigMap string
+}
+
+// registryStoreTimeout bounds the time to load or save a metric registry, so that a slow API server
+// doesn't block the start of the adapter
+const registryStoreTimeout = 10 * time.Second
+
+// registryStore persists the metric registries by the names of backends
+type registryStore interface {
+	load(ctx context.Context, backend string) ([]*swctlapi.MetricDefinition, error)
+	save(ctx context.Context, backend string, mdd []*swctlapi.MetricDefinition) error
+}
+
+var configMapResource = apischema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newRegistryStore(opts RegistryOptions, client dynamic.Interface) (registryStore, error) {
+	switch {
+	case opts.File != "" && opts.ConfigMap != "":
+		return nil, fmt.Errorf("the registry could be persisted to either a file or a ConfigMap")
+	case opts.File != "":
+		return &fileRegistryStore{path: opts.File}, nil
+	case opts.ConfigMap != "":
+		nn := strings.Split(opts.ConfigMap, "/")
+		if len(nn) != 2 || nn[0] == "" || nn[1] == "" {
+			return nil, fmt.Errorf("invalid ConfigMap %s, the format is namespace/name", opts.ConfigMap)
+		}
+		return &configMapRegistryStore{
+			client: client.Resource(configMapResource).Namespace(nn[0]),
+			name:   nn[1],
+		}, nil
+	}
+	return nil, nil
+}
+
+// fileRegistryStore keeps the registries of all backends in a JSON file
+type fileRegistryStore struct {
+	path string
+	lock sync.Mutex
+}
+
+func (s *fileRegistryStore) read() (map[string][]*swctlapi.MetricDefinition, error) {
+	registries := make(map[string][]*swctlapi.MetricDefinition)
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return registries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &registries); err != nil {
+		return nil, fmt.Errorf("invalid registry file %s: %v", s.path, err)
+	}
+	return registries, nil
+}
+
+func (s *fileRegistryStore) load(_ context.Context, backend string) ([]*swctlapi.MetricDefinition, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	registries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return registries[backend], nil
+}
+
+// save writes a temporary file then renames it, so that the file is never truncated
+func (s *fileRegistryStore) save(_ context.Context, backend string, mdd []*swctlapi.MetricDefinition) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	registries, err := s.read()
+	if err != nil {
+		return err
+	}
+	registries[backend] = mdd
+	content, err := json.Marshal(registries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// configMapRegistryStore keeps the registry of each backend in the key `<backend>.json` of a ConfigMap
+type configMapRegistryStore struct {
+	client dynamic.ResourceInterface
+	name   string
+}
+
+func (s *configMapRegistryStore) load(ctx context.Context, backend string) ([]*swctlapi.MetricDefinition, error) {
+	cm, err := s.client.Get(ctx, s.name, metav1.GetOptions{})
+	if apierr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	content, found, err := unstructured.NestedString(cm.Object, "data", backend+".json")
+	if err != nil || !found {
+		return nil, err
+	}
+	var mdd []*swctlapi.MetricDefinition
+	if err := json.Unmarshal([]byte(content), &mdd); err != nil {
+		return nil, fmt.Errorf("invalid registry of backend %s in ConfigMap %s: %v", backend, s.name, err)
+	}
+	return mdd, nil
+}
+
+func (s *configMapRegistryStore) save(ctx context.Context, backend string, mdd []*swctlapi.MetricDefinition) error {
+	content, err := json.Marshal(mdd)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{backend + ".json": string(content)}
+	patch, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Patch(ctx, s.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierr.IsNotFound(err) {
+		return err
+	}
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": s.name},
+		"data":       data,
+	}}
+	_, err = s.client.Create(ctx, cm, metav1.CreateOptions{})
+	return err
+}
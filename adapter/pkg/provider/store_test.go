@@ -0,0 +1,159 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestNewRegistryStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RegistryOptions
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "not persisted", wantNil: true},
+		{name: "file", opts: RegistryOptions{File: "/tmp/registry.json"}},
+		{name: "ConfigMap", opts: RegistryOptions{ConfigMap: "skywalking/registry"}},
+		{name: "both", opts: RegistryOptions{File: "/tmp/registry.json", ConfigMap: "skywalking/registry"}, wantErr: true},
+		{name: "ConfigMap without the namespace", opts: RegistryOptions{ConfigMap: "registry"}, wantErr: true},
+		{name: "ConfigMap without the name", opts: RegistryOptions{ConfigMap: "skywalking/"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newRegistryStore(tt.opts, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRegistryStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (store == nil) != tt.wantNil {
+				t.Errorf("newRegistryStore() = %v, want nil %t", store, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRegistryStoreRoundTrip(t *testing.T) {
+	songs := []*swctlapi.MetricDefinition{
+		{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue},
+		{Name: "service_percentile", Type: swctlapi.MetricsTypeLabeledValue},
+	}
+	books := []*swctlapi.MetricDefinition{{Name: "endpoint_cpm", Type: swctlapi.MetricsTypeRegularValue}}
+	tests := []struct {
+		name string
+		opts func(t *testing.T) RegistryOptions
+	}{
+		{name: "file", opts: func(t *testing.T) RegistryOptions {
+			return RegistryOptions{File: filepath.Join(t.TempDir(), "registry.json")}
+		}},
+		{name: "ConfigMap", opts: func(*testing.T) RegistryOptions {
+			return RegistryOptions{ConfigMap: "skywalking/registry"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newRegistryStore(tt.opts(t), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()))
+			if err != nil {
+				t.Fatalf("newRegistryStore() error = %v", err)
+			}
+			ctx := context.Background()
+			load := func(backend string) string {
+				mdd, err := store.load(ctx, backend)
+				if err != nil {
+					t.Fatalf("load(%s) error = %v", backend, err)
+				}
+				return display(mdd)
+			}
+
+			// the file or ConfigMap is missing on the first boot
+			if got := load("songs"); got != display([]*swctlapi.MetricDefinition(nil)) {
+				t.Errorf("load() on the first boot = %s", got)
+			}
+			for backend, mdd := range map[string][]*swctlapi.MetricDefinition{"songs": songs[:1], "books": books} {
+				if err := store.save(ctx, backend, mdd); err != nil {
+					t.Fatalf("save(%s) error = %v", backend, err)
+				}
+			}
+			// the registry of a backend is replaced without touching the others
+			if err := store.save(ctx, "songs", songs); err != nil {
+				t.Fatalf("save() error = %v", err)
+			}
+			if got, want := load("songs"), display(songs); got != want {
+				t.Errorf("load(songs) = %s, want %s", got, want)
+			}
+			if got, want := load("books"), display(books); got != want {
+				t.Errorf("load(books) = %s, want %s", got, want)
+			}
+			if got := load("movies"); got != display([]*swctlapi.MetricDefinition(nil)) {
+				t.Errorf("load(movies) = %s, want none", got)
+			}
+		})
+	}
+}
+
+// deadlineStore records the time left before the deadlines of the contexts
+type deadlineStore struct {
+	timeouts []time.Duration
+}
+
+func (s *deadlineStore) record(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		s.timeouts = append(s.timeouts, 0)
+		return
+	}
+	s.timeouts = append(s.timeouts, time.Until(deadline))
+}
+
+func (s *deadlineStore) load(ctx context.Context, _ string) ([]*swctlapi.MetricDefinition, error) {
+	s.record(ctx)
+	return nil, context.DeadlineExceeded
+}
+
+func (s *deadlineStore) save(ctx context.Context, _ string, _ []*swctlapi.MetricDefinition) error {
+	s.record(ctx)
+	return context.DeadlineExceeded
+}
+
+func TestRegistryStoreTimeout(t *testing.T) {
+	store := &deadlineStore{}
+	b := &backend{name: "oap", store: store}
+	b.restore()
+	b.persist([]*swctlapi.MetricDefinition{{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue}})
+	if len(store.timeouts) != 2 {
+		t.Fatalf("the store is accessed %d times, want 2", len(store.timeouts))
+	}
+	for _, timeout := range store.timeouts {
+		if timeout <= registryStoreTimeout-time.Second || timeout > registryStoreTimeout {
+			t.Errorf("the store is accessed with timeout %s, want %s", timeout, registryStoreTimeout)
+		}
+	}
+	if b.ready.Load() {
+		t.Error("the backend is ready after failing to restore")
+	}
+	if b.persisted != "" {
+		t.Error("the registry is taken as persisted after failing to save")
+	}
+}
@@ -0,0 +1,253 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// watchedFile caches the content of a file until its modification time or size changes
+type watchedFile struct {
+	path    string
+	lock    sync.Mutex
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+func newWatchedFile(path string) *watchedFile {
+	if path == "" {
+		return nil
+	}
+	return &watchedFile{path: path}
+}
+
+// read returns the content of the file, and whether it's reloaded
+func (f *watchedFile) read() ([]byte, bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat %s: %v", f.path, err)
+	}
+	if f.content != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.content, false, nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %v", f.path, err)
+	}
+	f.content, f.modTime, f.size = content, info.ModTime(), info.Size()
+	return f.content, true, nil
+}
+
+func (f *watchedFile) readString() (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	content, _, err := f.read()
+	return string(bytes.TrimSpace(content)), err
+}
+
+// authTransport sets the Authorization header of requests with the latest credentials
+type authTransport struct {
+	base     http.RoundTripper
+	username *watchedFile
+	password *watchedFile
+	token    *watchedFile
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorization, err := t.authorization()
+	if err != nil {
+		return nil, err
+	}
+	if authorization != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", authorization)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *authTransport) authorization() (string, error) {
+	token, err := t.token.readString()
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		return "Bearer " + token, nil
+	}
+	username, err := t.username.readString()
+	if err != nil {
+		return "", err
+	}
+	password, err := t.password.readString()
+	if err != nil {
+		return "", err
+	}
+	if username == "" && password == "" {
+		return "", nil
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+}
+
+// tlsFiles loads the CA bundle and client certificate of TLS connections on change
+type tlsFiles struct {
+	ca   *watchedFile
+	cert *watchedFile
+	key  *watchedFile
+	// host is the host of OAP cluster, whose IP addresses are verified since they are not sent as the server names
+	host string
+	// onReload is invoked once any of the files changes
+	onReload func()
+
+	lock       sync.Mutex
+	pool       *x509.CertPool
+	clientCert *tls.Certificate
+}
+
+func (f *tlsFiles) certPool() (*x509.CertPool, error) {
+	content, reloaded, err := f.ca.read()
+	if err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if reloaded || f.pool == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no valid certificate in %s", f.ca.path)
+		}
+		f.pool = pool
+		if reloaded && f.onReload != nil {
+			f.onReload()
+		}
+	}
+	return f.pool, nil
+}
+
+func (f *tlsFiles) certificate() (*tls.Certificate, error) {
+	certPEM, certReloaded, err := f.cert.read()
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, keyReloaded, err := f.key.read()
+	if err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if certReloaded || keyReloaded || f.clientCert == nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		f.clientCert = &cert
+		if f.onReload != nil {
+			f.onReload()
+		}
+	}
+	return f.clientCert, nil
+}
+
+// verifyConnection verifies the certificate chain of OAP cluster with the latest CA bundle
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate is presented by %s", cs.ServerName)
+	}
+	pool, err := f.certPool()
+	if err != nil {
+		return err
+	}
+	serverName := cs.ServerName
+	if serverName == "" {
+		serverName = f.host
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// newHTTPClient builds the client to connect OAP cluster at the address with the auth options, which keeps
+// at most maxIdleConns idle connections for reuse
+func newHTTPClient(address string, auth config.AuthConfig, maxIdleConns int) (*http.Client, error) {
+	if (auth.CertFile == "") != (auth.KeyFile == "") {
+		return nil, fmt.Errorf("the client certificate and key must be specified together")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %v", address, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	files := &tlsFiles{
+		ca:       newWatchedFile(auth.CAFile),
+		cert:     newWatchedFile(auth.CertFile),
+		key:      newWatchedFile(auth.KeyFile),
+		host:     u.Hostname(),
+		onReload: transport.CloseIdleConnections,
+	}
+	if files.ca != nil || files.cert != nil {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if files.ca != nil {
+			if _, err := files.certPool(); err != nil {
+				return nil, err
+			}
+			// the built-in verification is replaced to pick up the renewed CA bundle
+			tlsConfig.InsecureSkipVerify = true
+			tlsConfig.VerifyConnection = files.verifyConnection
+		}
+		if files.cert != nil {
+			if _, err := files.certificate(); err != nil {
+				return nil, err
+			}
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return files.certificate()
+			}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Transport: &authTransport{
+			base:     transport,
+			username: newWatchedFile(auth.UsernameFile),
+			password: newWatchedFile(auth.PasswordFile),
+			token:    newWatchedFile(auth.TokenFile),
+		},
+	}, nil
+}
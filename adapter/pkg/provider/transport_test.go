@@ -0,0 +1,261 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/skywalking-swck/adapter/pkg/config"
+)
+
+// testCA issues the certificates of the test servers
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create the CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse the CA: %v", err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a server certificate of the host, which is either a DNS name or an IP address
+func (ca *testCA) issue(t *testing.T, host string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to issue the certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeFile writes the content and moves the modification time forward, so that the change is always seen
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	info, statErr := os.Stat(path)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if statErr == nil {
+		modTime := info.ModTime().Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to touch %s: %v", path, err)
+		}
+	}
+}
+
+// dialTo sends all the connections of the client to the address whatever the host of the URL is
+func dialTo(t *testing.T, client *http.Client, addr string) {
+	t.Helper()
+	transport := client.Transport.(*authTransport).base.(*http.Transport)
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	trusted := newTestCA(t, "trusted")
+	untrusted := newTestCA(t, "untrusted")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{trusted.issue(t, "oap.example")}, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caFile, untrusted.pem)
+	client, err := newHTTPClient("https://"+net.JoinHostPort("oap.example", port), config.AuthConfig{CAFile: caFile}, 1)
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	dialTo(t, client, server.Listener.Addr().String())
+	get := func(host string) error {
+		resp, err := client.Get("https://" + net.JoinHostPort(host, port))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get("oap.example"); !errors.As(err, &x509.UnknownAuthorityError{}) {
+		t.Errorf("the certificate of the untrusted CA isn't rejected: %v", err)
+	}
+	writeFile(t, caFile, trusted.pem)
+	if err := get("oap.example"); err != nil {
+		t.Errorf("the certificate isn't accepted after the CA file is rotated: %v", err)
+	}
+	if err := get("other.example"); !errors.As(err, &x509.HostnameError{}) {
+		t.Errorf("the certificate of another server name isn't rejected: %v", err)
+	}
+}
+
+func TestTLSVerificationOfIP(t *testing.T) {
+	ca := newTestCA(t, "trusted")
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{name: "the IP address matches", host: "127.0.0.1"},
+		// the IP addresses are not sent as the server names, so they are verified against the address of OAP cluster
+		{name: "the certificate of a DNS name", host: "oap.example", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, tt.host)}, MinVersion: tls.VersionTLS12}
+			server.StartTLS()
+			defer server.Close()
+			client, err := newHTTPClient(server.URL, config.AuthConfig{CAFile: caFile}, 1)
+			if err != nil {
+				t.Fatalf("newHTTPClient() error = %v", err)
+			}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.wantErr != errors.As(err, &x509.HostnameError{}) || (!tt.wantErr && err != nil) {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	username, password, token := filepath.Join(dir, "username"), filepath.Join(dir, "password"), filepath.Join(dir, "token")
+	writeFile(t, username, []byte("admin\n"))
+	writeFile(t, password, []byte("secret\n"))
+	writeFile(t, token, []byte("token-1\n"))
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:secret"))
+
+	authorization := func(t *testing.T, auth config.AuthConfig) string {
+		client, err := newHTTPClient(server.URL, auth, 1)
+		if err != nil {
+			t.Fatalf("newHTTPClient() error = %v", err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("failed to request: %v", err)
+		}
+		defer resp.Body.Close()
+		var buf [256]byte
+		n, _ := resp.Body.Read(buf[:])
+		return string(buf[:n])
+	}
+	tests := []struct {
+		name string
+		auth config.AuthConfig
+		want string
+	}{
+		{name: "no credentials"},
+		{name: "basic", auth: config.AuthConfig{UsernameFile: username, PasswordFile: password}, want: basic},
+		{
+			name: "the token takes precedence over basic",
+			auth: config.AuthConfig{UsernameFile: username, PasswordFile: password, TokenFile: token}, want: "Bearer token-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authorization(t, tt.auth); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("reload the changed files", func(t *testing.T) {
+		transport := &authTransport{base: http.DefaultTransport, token: newWatchedFile(token)}
+		client := &http.Client{Transport: transport}
+		for _, want := range []string{"token-1", "token-2"} {
+			writeFile(t, token, []byte(want))
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("failed to request: %v", err)
+			}
+			var buf [256]byte
+			n, _ := resp.Body.Read(buf[:])
+			resp.Body.Close()
+			if got := string(buf[:n]); got != "Bearer "+want {
+				t.Errorf("Authorization = %q, want %q", got, "Bearer "+want)
+			}
+		}
+	})
+}
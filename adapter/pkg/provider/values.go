@@ -0,0 +1,280 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	"github.com/apache/skywalking-cli/assets"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// metricsValues is the counterpart of swctlapi.MetricsValues which accepts non-integer values
+type metricsValues struct {
+	Label  *string      `json:"label"`
+	Values *floatValues `json:"values"`
+}
+
+type floatValues struct {
+	Values []*kvFloat `json:"values"`
+}
+
+type kvFloat struct {
+	ID string `json:"id"`
+	// Value is nil if the bucket is empty, which is told apart from a real zero
+	Value *float64 `json:"value"`
+	// IsEmptyValue is reported by OAP for the empty buckets of the metrics, whose values are zero
+	IsEmptyValue bool `json:"isEmptyValue"`
+}
+
+// metricsValuesQuery is the counterpart of MetricsValues.graphql, which also tells the empty buckets
+const metricsValuesQuery string = `query ($condition: MetricsCondition!, $duration: Duration!) {
+    result: readMetricsValues(condition: $condition, duration: $duration) {
+        label
+        values {
+            values {
+                value
+                isEmptyValue
+            }
+        }
+    }
+}`
+
+// labeledMetricsValuesQuery is the counterpart of LabeledMetricsValues.graphql, which also tells the empty buckets
+const labeledMetricsValuesQuery string = `query ($condition: MetricsCondition!, $labels: [String!]!, $duration: Duration!) {
+    result: readLabeledMetricsValues(condition: $condition, labels: $labels, duration: $duration) {
+        label
+        values {
+            values {
+                value
+                isEmptyValue
+            }
+        }
+    }
+}`
+
+// emptyValueField is the field telling the empty buckets, which is unknown to OAP before 9.5.0
+const emptyValueField string = "isEmptyValue"
+
+func (c *oapClient) linearValues(ctx context.Context, condition swctlapi.MetricsCondition,
+	duration swctlapi.Duration) (metricsValues, error) {
+	var response map[string]metricsValues
+
+	err := c.queryValues(ctx, "linear_values", metricsValuesQuery, assets.Read("graphqls/metrics/MetricsValues.graphql"),
+		map[string]interface{}{
+			"condition": condition,
+			"duration":  duration,
+		}, &response)
+
+	values := response["result"]
+	markEmptyValues(values)
+	return values, err
+}
+
+func (c *oapClient) multipleLinearValues(ctx context.Context, condition swctlapi.MetricsCondition, labels []string,
+	duration swctlapi.Duration) ([]metricsValues, error) {
+	var response map[string][]metricsValues
+
+	err := c.queryValues(ctx, "labeled_values", labeledMetricsValuesQuery, assets.Read("graphqls/metrics/LabeledMetricsValues.graphql"),
+		map[string]interface{}{
+			"duration":  duration,
+			"condition": condition,
+			"labels":    labels,
+		}, &response)
+
+	for _, values := range response["result"] {
+		markEmptyValues(values)
+	}
+	return response["result"], err
+}
+
+// queryValues queries the values with the empty buckets told. If OAP doesn't know the field telling them, the legacy
+// query is sent instead from then on, whose empty buckets are zero.
+func (c *oapClient) queryValues(ctx context.Context, operation, query, legacyQuery string, variables map[string]interface{},
+	response interface{}) error {
+	if !c.legacyValues.Load() {
+		err := c.query(ctx, operation, query, variables, response)
+		if err == nil || !strings.Contains(err.Error(), emptyValueField) {
+			return err
+		}
+		klog.Warningf("backend %s doesn't tell the empty buckets of the metrics, which are served as zero: %v", c.name, err)
+		c.legacyValues.Store(true)
+	}
+	return c.query(ctx, operation, legacyQuery, variables, response)
+}
+
+// markEmptyValues clears the values of the empty buckets
+func markEmptyValues(values metricsValues) {
+	if values.Values == nil {
+		return
+	}
+	for _, kv := range values.Values.Values {
+		if kv != nil && kv.IsEmptyValue {
+			kv.Value = nil
+		}
+	}
+}
+
+func (c *oapClient) listMetrics(ctx context.Context, regex string) ([]*swctlapi.MetricDefinition, error) {
+	var response map[string][]*swctlapi.MetricDefinition
+
+	err := c.query(ctx, "list_metrics", assets.Read("graphqls/metrics/ListMetrics.graphql"), map[string]interface{}{
+		"regex": regex,
+	}, &response)
+
+	return response["result"], err
+}
+
+// serviceQuery finds the service by its name
+const serviceQuery string = `query ($serviceName: String!) {
+    result: findService(serviceName: $serviceName) {
+        id
+        name
+        layers
+    }
+}`
+
+// serviceLayers returns the layers of the service, it's empty if the service doesn't exist
+func (c *oapClient) serviceLayers(ctx context.Context, serviceName string) ([]string, error) {
+	var response map[string]*struct {
+		Layers []string `json:"layers"`
+	}
+
+	if err := c.query(ctx, "find_service", serviceQuery, map[string]interface{}{
+		"serviceName": serviceName,
+	}, &response); err != nil {
+		return nil, err
+	}
+	if service := response["result"]; service != nil {
+		return service.Layers, nil
+	}
+	return nil, nil
+}
+
+// expressionQuery evaluates a MQE with the execExpression API of OAP
+const expressionQuery string = `query ($expression: String!, $entity: Entity!, $duration: Duration!) {
+    result: execExpression(expression: $expression, entity: $entity, duration: $duration) {
+        type
+        error
+        results {
+            metric {
+                labels {
+                    key
+                    value
+                }
+            }
+            values {
+                id
+                value
+            }
+        }
+    }
+}`
+
+// The types of expression results
+const (
+	expressionTypeSingleValue      string = "SINGLE_VALUE"
+	expressionTypeTimeSeriesValues string = "TIME_SERIES_VALUES"
+	expressionTypeSortedList       string = "SORTED_LIST"
+)
+
+type expressionResult struct {
+	Type    string      `json:"type"`
+	Error   *string     `json:"error"`
+	Results []mqeValues `json:"results"`
+}
+
+type mqeValues struct {
+	Metric struct {
+		Labels []swctlapi.KeyValue `json:"labels"`
+	} `json:"metric"`
+	Values []struct {
+		ID    *string `json:"id"`
+		Value *string `json:"value"`
+	} `json:"values"`
+}
+
+// execExpression evaluates the expression, and converts each result to metricsValues, whose label is
+// the joined values of the labels. The empty values are kept as nil.
+func (c *oapClient) execExpression(ctx context.Context, expression string, entity *swctlapi.Entity,
+	duration swctlapi.Duration) (string, []metricsValues, error) {
+	var response map[string]expressionResult
+
+	if err := c.query(ctx, "expression", expressionQuery, map[string]interface{}{
+		"expression": expression,
+		"entity":     entity,
+		"duration":   duration,
+	}, &response); err != nil {
+		return "", nil, err
+	}
+	result := response["result"]
+	if result.Error != nil && *result.Error != "" {
+		oapErrors.WithLabelValues(c.name, "expression").Inc()
+		return "", nil, fmt.Errorf("failed to evaluate expression %s: %s", expression, *result.Error)
+	}
+
+	values := make([]metricsValues, 0, len(result.Results))
+	for _, r := range result.Results {
+		labelValues := make([]string, 0, len(r.Metric.Labels))
+		for _, l := range r.Metric.Labels {
+			if l.Value != nil {
+				labelValues = append(labelValues, *l.Value)
+			}
+		}
+		label := strings.Join(labelValues, ",")
+		mv := metricsValues{Label: &label, Values: &floatValues{}}
+		for _, v := range r.Values {
+			kv := &kvFloat{}
+			if v.ID != nil {
+				kv.ID = *v.ID
+			}
+			if v.Value != nil && *v.Value != "" {
+				f, err := strconv.ParseFloat(*v.Value, 64)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid value %s of expression %s: %v", *v.Value, expression, err)
+				}
+				kv.Value = &f
+			}
+			mv.Values.Values = append(mv.Values.Values, kv)
+		}
+		values = append(values, mv)
+	}
+	return result.Type, values, nil
+}
+
+// newQuantity keeps integers as they are, and represents decimals in milli-units.
+// The values which are not finite or overflow int64 are rejected.
+func newQuantity(value float64) (*resource.Quantity, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("non-finite value %g", value)
+	}
+	if value == math.Trunc(value) {
+		if math.Abs(value) >= math.MaxInt64 {
+			return nil, fmt.Errorf("value %g is out of range", value)
+		}
+		return resource.NewQuantity(int64(value), resource.DecimalSI), nil
+	}
+	// the decimals are less than 2^53, which never overflow the milli-units
+	return resource.NewMilliQuantity(int64(math.Round(value*1000)), resource.DecimalSI), nil
+}
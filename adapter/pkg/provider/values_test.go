@@ -0,0 +1,322 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	swctlapi "github.com/apache/skywalking-cli/api"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apischema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// oapHandler serves a GraphQL query, it returns the data of the result, or the message of a GraphQL error
+type oapHandler func(query string, variables map[string]interface{}) (result interface{}, message string)
+
+// newFakeOAP starts an OAP server serving the queries by the handler, and returns the client of it without retries
+func newFakeOAP(t *testing.T, handler oapHandler) *oapClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, message := handler(req.Query, req.Variables)
+		response := map[string]interface{}{"data": map[string]interface{}{"result": result}}
+		if message != "" {
+			response = map[string]interface{}{"errors": []map[string]string{{"message": message}}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return newOAPClient("oap", server.URL, server.Client(), time.Second, 0)
+}
+
+// points builds the values of the GraphQL response, the nil points are the empty buckets reported as zero
+func points(label string, values ...*float64) map[string]interface{} {
+	vv := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			vv = append(vv, map[string]interface{}{"value": 0, "isEmptyValue": true})
+		} else {
+			vv = append(vv, map[string]interface{}{"value": *v, "isEmptyValue": false})
+		}
+	}
+	return map[string]interface{}{"label": label, "values": map[string]interface{}{"values": vv}}
+}
+
+// legacyOAP rejects the field telling the empty buckets like OAP before 9.5.0, and serves the values otherwise
+func legacyOAP(queries *atomic.Int32, result interface{}) oapHandler {
+	return func(query string, _ map[string]interface{}) (interface{}, string) {
+		queries.Add(1)
+		if strings.Contains(query, emptyValueField) {
+			return nil, "Validation error (FieldUndefined@[result/values/values/isEmptyValue]) : " +
+				"Field 'isEmptyValue' in type 'KVInt' is undefined"
+		}
+		return result, ""
+	}
+}
+
+func TestLinearValues(t *testing.T) {
+	t.Run("empty buckets are told apart from zero", func(t *testing.T) {
+		c := newFakeOAP(t, func(query string, _ map[string]interface{}) (interface{}, string) {
+			if !strings.Contains(query, "readMetricsValues") || !strings.Contains(query, emptyValueField) {
+				return nil, "unexpected query " + query
+			}
+			return points("", value(1), nil, value(0)), ""
+		})
+		values, err := c.linearValues(context.Background(), swctlapi.MetricsCondition{}, swctlapi.Duration{})
+		if err != nil {
+			t.Fatalf("linearValues() error = %v", err)
+		}
+		assertPoints(t, values, value(1), nil, value(0))
+	})
+	t.Run("legacy OAP counts empty buckets as zero", func(t *testing.T) {
+		var queries atomic.Int32
+		c := newFakeOAP(t, legacyOAP(&queries, points("", value(1), value(0))))
+		for i := 0; i < 2; i++ {
+			values, err := c.linearValues(context.Background(), swctlapi.MetricsCondition{}, swctlapi.Duration{})
+			if err != nil {
+				t.Fatalf("linearValues() error = %v", err)
+			}
+			assertPoints(t, values, value(1), value(0))
+		}
+		// the field is only tried once
+		if n := queries.Load(); n != 3 {
+			t.Errorf("queries = %d, want 3", n)
+		}
+	})
+}
+
+func TestMultipleLinearValues(t *testing.T) {
+	c := newFakeOAP(t, func(query string, variables map[string]interface{}) (interface{}, string) {
+		if !strings.Contains(query, "readLabeledMetricsValues") || !strings.Contains(query, emptyValueField) {
+			return nil, "unexpected query " + query
+		}
+		return []interface{}{points("p50", value(3), nil), points("p99", nil, value(9))}, ""
+	})
+	values, err := c.multipleLinearValues(context.Background(), swctlapi.MetricsCondition{}, []string{"p50", "p99"},
+		swctlapi.Duration{})
+	if err != nil {
+		t.Fatalf("multipleLinearValues() error = %v", err)
+	}
+	if len(values) != 2 {
+		t.Fatalf("multipleLinearValues() = %s, want 2 labels", display(values))
+	}
+	assertPoints(t, values[0], value(3), nil)
+	assertPoints(t, values[1], nil, value(9))
+}
+
+// TestReadMetricValuesStale goes through the regular metrics read from OAP, whose empty latest bucket is stale
+func TestReadMetricValuesStale(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  []*float64
+		policy  StalePolicy
+		want    float64
+		wantErr func(error) bool
+	}{
+		{name: "the latest bucket is zero", points: []*float64{value(5), value(0), value(1)}, policy: StalePolicyError, want: 0},
+		{name: "the latest bucket is empty", points: []*float64{value(5), nil, value(1)}, policy: StalePolicyError,
+			wantErr: apierr.IsServiceUnavailable},
+		{name: "the latest non-empty bucket is served", points: []*float64{value(5), nil, nil}, policy: StalePolicyLast, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeOAP(t, func(string, map[string]interface{}) (interface{}, string) {
+				return points("", tt.points...), ""
+			})
+			p := &externalMetricsProvider{cache: newQueryCache(0, 0)}
+			opts := defaultQueryOptions
+			opts.stalePolicy = tt.policy
+			md := &swctlapi.MetricDefinition{Name: "service_cpm", Type: swctlapi.MetricsTypeRegularValue}
+			entity := newEntity(ptrTo("songs"), ptrTo(""), ptrTo(""))
+			got, err := p.readMetricValues(context.Background(), &backend{name: "oap", oap: c}, md, entity, nil, opts,
+				apischema.GroupResource{}, "service_cpm")
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("readMetricValues() error = %v, want a matched error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readMetricValues() error = %v", err)
+			}
+			if len(got) != 1 || got[0].value != tt.want {
+				t.Errorf("readMetricValues() = %+v, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+// assertPoints checks the points of the values, the nil points are the empty buckets
+func assertPoints(t *testing.T, values metricsValues, want ...*float64) {
+	t.Helper()
+	if values.Values == nil || len(values.Values.Values) != len(want) {
+		t.Fatalf("values = %s, want %d points", display(values), len(want))
+	}
+	for i, kv := range values.Values.Values {
+		if (kv.Value == nil) != (want[i] == nil) || (want[i] != nil && *kv.Value != *want[i]) {
+			t.Errorf("point %d = %s, want %s", i, display(kv.Value), display(want[i]))
+		}
+	}
+}
+
+func TestNewQuantity(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		want    string
+		wantErr bool
+	}{
+		{name: "integer", value: 42, want: "42"},
+		{name: "zero", value: 0, want: "0"},
+		{name: "negative integer", value: -3, want: "-3"},
+		{name: "decimal in milli-units", value: 0.25, want: "250m"},
+		{name: "decimal rounded to milli-units", value: 1.23456, want: "1235m"},
+		{name: "NaN", value: math.NaN(), wantErr: true},
+		{name: "positive infinity", value: math.Inf(1), wantErr: true},
+		{name: "negative infinity", value: math.Inf(-1), wantErr: true},
+		{name: "large integer", value: 1e17, want: "100P"},
+		{name: "overflow of integers", value: 1e19, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newQuantity(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newQuantity(%g) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("newQuantity(%g) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// mqeResult builds a result of the expression with the label values and the values, the nil values are empty
+func mqeResult(labelValues []string, values ...*string) map[string]interface{} {
+	labels := make([]map[string]interface{}, 0, len(labelValues))
+	for i, v := range labelValues {
+		labels = append(labels, map[string]interface{}{"key": fmt.Sprintf("key%d", i), "value": v})
+	}
+	vv := make([]map[string]interface{}, 0, len(values))
+	for i, v := range values {
+		vv = append(vv, map[string]interface{}{"id": fmt.Sprint(i), "value": v})
+	}
+	return map[string]interface{}{"metric": map[string]interface{}{"labels": labels}, "values": vv}
+}
+
+func TestExecExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   map[string]interface{}
+		wantType string
+		// want are the points by the labels
+		want    map[string][]*float64
+		wantErr bool
+	}{
+		{
+			name: "time series",
+			result: map[string]interface{}{
+				"type":    expressionTypeTimeSeriesValues,
+				"results": []interface{}{mqeResult(nil, ptrTo("1.5"), nil, ptrTo(""), ptrTo("0"))},
+			},
+			wantType: expressionTypeTimeSeriesValues,
+			want:     map[string][]*float64{"": {value(1.5), nil, nil, value(0)}},
+		},
+		{
+			name: "the label values are joined",
+			result: map[string]interface{}{
+				"type": expressionTypeTimeSeriesValues,
+				"results": []interface{}{
+					mqeResult([]string{"50", "GET:/songs"}, ptrTo("10")),
+					mqeResult([]string{"99", "GET:/songs"}, ptrTo("20")),
+				},
+			},
+			wantType: expressionTypeTimeSeriesValues,
+			want:     map[string][]*float64{"50,GET:/songs": {value(10)}, "99,GET:/songs": {value(20)}},
+		},
+		{
+			name: "sorted list",
+			result: map[string]interface{}{
+				"type":    expressionTypeSortedList,
+				"results": []interface{}{mqeResult(nil, ptrTo("3"), ptrTo("2"))},
+			},
+			wantType: expressionTypeSortedList,
+			want:     map[string][]*float64{"": {value(3), value(2)}},
+		},
+		{
+			name:    "the error of the expression",
+			result:  map[string]interface{}{"type": "UNKNOWN", "error": "Metric: [missing] dose not exist."},
+			wantErr: true,
+		},
+		{
+			name: "invalid value",
+			result: map[string]interface{}{
+				"type":    expressionTypeSingleValue,
+				"results": []interface{}{mqeResult(nil, ptrTo("NaN%"))},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeOAP(t, func(query string, variables map[string]interface{}) (interface{}, string) {
+				if !strings.Contains(query, "execExpression") || variables["expression"] != "service_sla / 100" {
+					return nil, "unexpected query " + query
+				}
+				return tt.result, ""
+			})
+			resultType, values, err := c.execExpression(context.Background(), "service_sla / 100",
+				&swctlapi.Entity{}, swctlapi.Duration{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resultType != tt.wantType {
+				t.Errorf("execExpression() type = %s, want %s", resultType, tt.wantType)
+			}
+			if len(values) != len(tt.want) {
+				t.Fatalf("execExpression() = %d results, want %d", len(values), len(tt.want))
+			}
+			for _, v := range values {
+				want, found := tt.want[*v.Label]
+				if !found {
+					t.Errorf("unexpected result of label %q", *v.Label)
+					continue
+				}
+				assertPoints(t, v, want...)
+			}
+		})
+	}
+}
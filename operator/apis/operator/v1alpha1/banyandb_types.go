@@ -18,7 +18,6 @@
 package v1alpha1
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -56,6 +55,9 @@ type BanyanDBSpec struct {
 	// BanyanDB Storage
 	// +kubebuilder:validation:Optional
 	Storages []StorageConfig `json:"storages,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 type StorageConfig struct {
@@ -75,9 +77,20 @@ type StorageConfig struct {
 // BanyanDBStatus defines the observed state of BanyanDB
 type BanyanDBStatus struct {
 	AvailableReplicas int32 `json:"available_pods,omitempty"`
-	// Represents the latest available observations of the underlying statefulset's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
+	// +kubebuilder:validation:Optional
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
 	// +kubebuilder:validation:Optional
-	Conditions []appsv1.DeploymentCondition `json:"conditions,omitempty"`
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -104,3 +117,28 @@ type BanyanDBList struct {
 func init() {
 	SchemeBuilder.Register(&BanyanDB{}, &BanyanDBList{})
 }
+
+// GetInventory returns the resources applied for the BanyanDB
+func (in *BanyanDB) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the BanyanDB
+func (in *BanyanDB) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the BanyanDB
+func (in *BanyanDB) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the BanyanDB
+func (in *BanyanDB) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the BanyanDB
+func (in *BanyanDB) GetPodTemplate() *corev1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
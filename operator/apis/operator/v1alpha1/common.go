@@ -20,6 +20,7 @@ package v1alpha1
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 )
 
@@ -117,3 +118,68 @@ type Ingress struct {
 	// +kubebuilder:validation:Optional
 	TLS []networkingv1.IngressTLS `json:"tls,omitempty" protobuf:"bytes,2,rep,name=tls"`
 }
+
+// ResourceRef refers to a resource applied by the operator for a CR
+type ResourceRef struct {
+	// APIVersion of the resource
+	// +kubebuilder:validation:Required
+	APIVersion string `json:"apiVersion"`
+	// Kind of the resource
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+	// Namespace of the resource, empty if it's cluster-scoped
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the resource
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// TemplateOverride tells where the templates overriding the embedded ones come from
+type TemplateOverride struct {
+	// Type of the source, which is Directory or ConfigMap
+	// +kubebuilder:validation:Required
+	Type string `json:"type"`
+	// Name of the source, which is the path of the directory or the namespaced name of the ConfigMap
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Version is the hash of the templates from the source
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+	// Files overridden or added by the source
+	// +kubebuilder:validation:Optional
+	Files []string `json:"files,omitempty"`
+}
+
+// Workload contains the common settings of the workloads of a component
+type Workload struct {
+	// PodTemplate is merged into the pod template of the workloads by strategic merge patch, e.g. to set
+	// the resources, node selector, tolerations, affinity, security context and priority class, or to add
+	// volumes and sidecars. The containers are merged by their names.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+}
+
+const (
+	// ConditionTypeReady means the resources of a CR are applied and all of them are ready
+	ConditionTypeReady = "Ready"
+	// ConditionTypeProgressing means the resources of a CR are applied but some of them are not ready yet
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded means the last reconciliation of a CR failed
+	ConditionTypeDegraded = "Degraded"
+)
+
+const (
+	// ReasonAvailable means all of the desired ones are ready
+	ReasonAvailable = "Available"
+	// ReasonInProgress means some of the desired ones are not ready yet
+	ReasonInProgress = "InProgress"
+	// ReasonApplyFailed means the resources failed to be applied, the reason of the api error is used
+	// instead if there is one, such as ApplyForbidden and ApplyInvalid
+	ReasonApplyFailed = "ApplyFailed"
+	// ReasonFinalizing means the CR is being deleted, and its cluster-scoped or unowned resources are being cleaned up
+	ReasonFinalizing = "Finalizing"
+)
@@ -18,7 +18,7 @@
 package v1alpha1
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -35,6 +35,9 @@ type EventExporterSpec struct {
 	// Config of filters and exporters
 	// +kubebuilder:validation:Optional
 	Config string `json:"config,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 // EventExporterStatus defines the observed state of EventExporter
@@ -42,12 +45,23 @@ type EventExporterStatus struct {
 	// Total number of available pods targeted by this deployment.
 	// +kubebuilder:validation:Optional
 	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
-	// Represents the latest available observations of the underlying deployment's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
 	// +kubebuilder:validation:Optional
-	Conditions []appsv1.DeploymentCondition `json:"conditions,omitempty"`
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// Name of the configMap.
 	// +kubebuilder:validation:Optional
 	ConfigMapName string `json:"configMapName,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
+	// +kubebuilder:validation:Optional
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
+	// +kubebuilder:validation:Optional
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -77,3 +91,38 @@ type EventExporterList struct {
 func init() {
 	SchemeBuilder.Register(&EventExporter{}, &EventExporterList{})
 }
+
+// GetInventory returns the resources applied for the EventExporter
+func (in *EventExporter) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the EventExporter
+func (in *EventExporter) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the EventExporter
+func (in *EventExporter) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the EventExporter
+func (in *EventExporter) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the EventExporter
+func (in *EventExporter) GetPodTemplate() *corev1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
+
+// GetConditions returns the conditions of the EventExporter
+func (in *EventExporter) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions records the conditions of the EventExporter
+func (in *EventExporter) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
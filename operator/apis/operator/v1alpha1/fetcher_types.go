@@ -33,6 +33,9 @@ type FetcherSpec struct {
 	// ClusterName
 	// +kubebuilder:validation:Optional
 	ClusterName string `json:"clusterName,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 // FetcherType Type string describes ingress methods for a service
@@ -55,29 +58,20 @@ type FetcherStatus struct {
 	// Replicas is currently not being set and might be removed in the next version.
 	// +kubebuilder:validation:Optional
 	Replicas int32 `json:"replicas,omitempty"`
-	// Represents the latest available observations of a fetcher's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
 	// +kubebuilder:validation:Optional
-	Conditions []FetcherCondition `json:"conditions,omitempty"`
-}
-
-type FetcherConditionType string
-
-var (
-	FetcherConditionTypeRead FetcherConditionType = "Ready"
-)
-
-// FetcherCondition describes the state of a deployment at a certain point.
-type FetcherCondition struct {
-	// Type of deployment condition.
-	Type FetcherConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=DeploymentConditionType"`
-	// Status of the condition, one of True, False, Unknown.
-	Status v1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status,casttype=k8s.io/api/core/v1.ConditionStatus"`
-	// The last time this condition was updated.
-	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty" protobuf:"bytes,6,opt,name=lastUpdateTime"`
-	// Last time the condition transitioned from one status to another.
-	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,7,opt,name=lastTransitionTime"`
-	// A human readable message indicating details about the transition.
-	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
+	// +kubebuilder:validation:Optional
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
+	// +kubebuilder:validation:Optional
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -104,3 +98,38 @@ type FetcherList struct {
 func init() {
 	SchemeBuilder.Register(&Fetcher{}, &FetcherList{})
 }
+
+// GetInventory returns the resources applied for the Fetcher
+func (in *Fetcher) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the Fetcher
+func (in *Fetcher) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the Fetcher
+func (in *Fetcher) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the Fetcher
+func (in *Fetcher) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the Fetcher
+func (in *Fetcher) GetPodTemplate() *v1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
+
+// GetConditions returns the conditions of the Fetcher
+func (in *Fetcher) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions records the conditions of the Fetcher
+func (in *Fetcher) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
@@ -47,6 +47,14 @@ type JavaAgentStatus struct {
 	CreationTime metav1.Time `json:"creationTime,omitempty"`
 	// The last time this condition was updated.
 	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
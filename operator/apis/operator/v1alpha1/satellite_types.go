@@ -18,7 +18,6 @@
 package v1alpha1
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -41,6 +40,9 @@ type SatelliteSpec struct {
 	// Backend OAP server name
 	// +kubebuilder:validation:Optional
 	OAPServerName string `json:"OAPServerName,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 // SatelliteStatus defines the observed state of Satellite
@@ -51,9 +53,20 @@ type SatelliteStatus struct {
 	// Address indicates the entry of Satellite server which ingresses data
 	// +kubebuilder:validation:Optional
 	Address string `json:"address,omitempty"`
-	// Represents the latest available observations of the underlying deployment's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
 	// +kubebuilder:validation:Optional
-	Conditions []appsv1.DeploymentCondition `json:"conditions,omitempty"`
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
+	// +kubebuilder:validation:Optional
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -85,3 +98,38 @@ type SatelliteList struct {
 func init() {
 	SchemeBuilder.Register(&Satellite{}, &SatelliteList{})
 }
+
+// GetInventory returns the resources applied for the Satellite
+func (in *Satellite) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the Satellite
+func (in *Satellite) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the Satellite
+func (in *Satellite) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the Satellite
+func (in *Satellite) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the Satellite
+func (in *Satellite) GetPodTemplate() *corev1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
+
+// GetConditions returns the conditions of the Satellite
+func (in *Satellite) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions records the conditions of the Satellite
+func (in *Satellite) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
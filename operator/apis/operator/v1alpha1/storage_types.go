@@ -18,7 +18,6 @@
 package v1alpha1
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -52,6 +51,9 @@ type StorageSpec struct {
 	Config []corev1.EnvVar `json:"config,omitempty"`
 	//ResourceCnfig relevant settings
 	ResourceCnfig Resource `json:"resource,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 // SecuritySpec defines the security setting of Storage
@@ -78,9 +80,20 @@ type Resource struct {
 
 // StorageStatus defines the observed state of Storage
 type StorageStatus struct {
-	// Represents the latest available observations of the underlying statefulset's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
 	// +kubebuilder:validation:Optional
-	Conditions []appsv1.StatefulSetCondition `json:"conditions,omitempty"`
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
+	// +kubebuilder:validation:Optional
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -112,3 +125,38 @@ type StorageList struct {
 func init() {
 	SchemeBuilder.Register(&Storage{}, &StorageList{})
 }
+
+// GetInventory returns the resources applied for the Storage
+func (in *Storage) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the Storage
+func (in *Storage) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the Storage
+func (in *Storage) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the Storage
+func (in *Storage) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the Storage
+func (in *Storage) GetPodTemplate() *corev1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
+
+// GetConditions returns the conditions of the Storage
+func (in *Storage) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions records the conditions of the Storage
+func (in *Storage) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
@@ -18,7 +18,7 @@
 package v1alpha1
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -60,6 +60,9 @@ type UISpec struct {
 	// Service relevant settings
 	// +kubebuilder:validation:Optional
 	Service Service `json:"service,omitempty"`
+	// Workload contains the common settings of the workloads
+	// +kubebuilder:validation:Optional
+	Workload `json:",inline"`
 }
 
 // UIStatus defines the observed state of UI
@@ -76,9 +79,20 @@ type UIStatus struct {
 	Ports []int32 `json:"ports"`
 	// +kubebuilder:validation:Optional
 	InternalAddress string `json:"internalAddress,omitempty"`
-	// Represents the latest available observations of the underlying deployment's current state.
+	// Conditions are the latest observations of the state, whose types are Ready, Progressing and Degraded.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec observed by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Inventory is the list of resources applied for this CR, the ones no longer rendered are pruned.
+	// +kubebuilder:validation:Optional
+	Inventory []ResourceRef `json:"inventory,omitempty"`
+	// TemplateOverrides are the sources of the templates overriding the embedded ones, which are layered in order.
 	// +kubebuilder:validation:Optional
-	Conditions []appsv1.DeploymentCondition `json:"conditions,omitempty"`
+	TemplateOverrides []TemplateOverride `json:"templateOverrides,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -112,3 +126,28 @@ type UIList struct {
 func init() {
 	SchemeBuilder.Register(&UI{}, &UIList{})
 }
+
+// GetInventory returns the resources applied for the UI
+func (in *UI) GetInventory() []ResourceRef {
+	return in.Status.Inventory
+}
+
+// SetInventory records the resources applied for the UI
+func (in *UI) SetInventory(inventory []ResourceRef) {
+	in.Status.Inventory = inventory
+}
+
+// GetTemplateOverrides returns the sources of the templates overriding the embedded ones of the UI
+func (in *UI) GetTemplateOverrides() []TemplateOverride {
+	return in.Status.TemplateOverrides
+}
+
+// SetTemplateOverrides records the sources of the templates overriding the embedded ones of the UI
+func (in *UI) SetTemplateOverrides(overrides []TemplateOverride) {
+	in.Status.TemplateOverrides = overrides
+}
+
+// GetPodTemplate returns the pod template merged into the workloads of the UI
+func (in *UI) GetPodTemplate() *corev1.PodTemplateSpec {
+	return in.Spec.PodTemplate
+}
@@ -22,9 +22,9 @@
 package v1alpha1
 
 import (
-	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -105,6 +105,7 @@ func (in *BanyanDBSpec) DeepCopyInto(out *BanyanDBSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BanyanDBSpec.
@@ -122,7 +123,19 @@ func (in *BanyanDBStatus) DeepCopyInto(out *BanyanDBStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.DeploymentCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -159,7 +172,7 @@ func (in *EventExporter) DeepCopyInto(out *EventExporter) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -216,6 +229,7 @@ func (in *EventExporterList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventExporterSpec) DeepCopyInto(out *EventExporterSpec) {
 	*out = *in
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventExporterSpec.
@@ -233,7 +247,19 @@ func (in *EventExporterStatus) DeepCopyInto(out *EventExporterStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.DeploymentCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -277,23 +303,6 @@ func (in *Fetcher) DeepCopyObject() runtime.Object {
 	return nil
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FetcherCondition) DeepCopyInto(out *FetcherCondition) {
-	*out = *in
-	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FetcherCondition.
-func (in *FetcherCondition) DeepCopy() *FetcherCondition {
-	if in == nil {
-		return nil
-	}
-	out := new(FetcherCondition)
-	in.DeepCopyInto(out)
-	return out
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FetcherList) DeepCopyInto(out *FetcherList) {
 	*out = *in
@@ -334,6 +343,7 @@ func (in *FetcherSpec) DeepCopyInto(out *FetcherSpec) {
 		*out = make([]FetcherType, len(*in))
 		copy(*out, *in)
 	}
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FetcherSpec.
@@ -351,7 +361,19 @@ func (in *FetcherStatus) DeepCopyInto(out *FetcherStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]FetcherCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -503,6 +525,13 @@ func (in *JavaAgentStatus) DeepCopyInto(out *JavaAgentStatus) {
 	*out = *in
 	in.CreationTime.DeepCopyInto(&out.CreationTime)
 	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JavaAgentStatus.
@@ -666,6 +695,13 @@ func (in *OAPServerConfigStatus) DeepCopyInto(out *OAPServerConfigStatus) {
 	*out = *in
 	in.CreationTime.DeepCopyInto(&out.CreationTime)
 	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAPServerConfigStatus.
@@ -762,6 +798,13 @@ func (in *OAPServerDynamicConfigStatus) DeepCopyInto(out *OAPServerDynamicConfig
 	*out = *in
 	in.CreationTime.DeepCopyInto(&out.CreationTime)
 	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAPServerDynamicConfigStatus.
@@ -822,6 +865,7 @@ func (in *OAPServerSpec) DeepCopyInto(out *OAPServerSpec) {
 		*out = new(RelevantStorage)
 		(*in).DeepCopyInto(*out)
 	}
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAPServerSpec.
@@ -839,7 +883,19 @@ func (in *OAPServerStatus) DeepCopyInto(out *OAPServerStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.DeploymentCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -891,6 +947,21 @@ func (in *Resource) DeepCopy() *Resource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRef) DeepCopyInto(out *ResourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRef.
+func (in *ResourceRef) DeepCopy() *ResourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Satellite) DeepCopyInto(out *Satellite) {
 	*out = *in
@@ -961,6 +1032,7 @@ func (in *SatelliteSpec) DeepCopyInto(out *SatelliteSpec) {
 		}
 	}
 	in.Service.DeepCopyInto(&out.Service)
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SatelliteSpec.
@@ -978,7 +1050,19 @@ func (in *SatelliteStatus) DeepCopyInto(out *SatelliteStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.DeploymentCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1155,6 +1239,7 @@ func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
 		}
 	}
 	out.ResourceCnfig = in.ResourceCnfig
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
@@ -1172,7 +1257,19 @@ func (in *StorageStatus) DeepCopyInto(out *StorageStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.StatefulSetCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1291,6 +1388,13 @@ func (in *SwAgentStatus) DeepCopyInto(out *SwAgentStatus) {
 	*out = *in
 	in.CreationTime.DeepCopyInto(&out.CreationTime)
 	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SwAgentStatus.
@@ -1303,6 +1407,26 @@ func (in *SwAgentStatus) DeepCopy() *SwAgentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateOverride) DeepCopyInto(out *TemplateOverride) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateOverride.
+func (in *TemplateOverride) DeepCopy() *TemplateOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UI) DeepCopyInto(out *UI) {
 	*out = *in
@@ -1366,6 +1490,7 @@ func (in *UIList) DeepCopyObject() runtime.Object {
 func (in *UISpec) DeepCopyInto(out *UISpec) {
 	*out = *in
 	in.Service.DeepCopyInto(&out.Service)
+	in.Workload.DeepCopyInto(&out.Workload)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UISpec.
@@ -1393,7 +1518,19 @@ func (in *UIStatus) DeepCopyInto(out *UIStatus) {
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.DeploymentCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateOverrides != nil {
+		in, out := &in.TemplateOverrides, &out.TemplateOverrides
+		*out = make([]TemplateOverride, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1424,3 +1561,23 @@ func (in *UserSpec) DeepCopy() *UserSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Workload) DeepCopyInto(out *Workload) {
+	*out = *in
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(corev1.PodTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Workload.
+func (in *Workload) DeepCopy() *Workload {
+	if in == nil {
+		return nil
+	}
+	out := new(Workload)
+	in.DeepCopyInto(out)
+	return out
+}
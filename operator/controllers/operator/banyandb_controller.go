@@ -23,12 +23,14 @@ import (
 
 	"github.com/go-logr/logr"
 	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
 	apiequal "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -57,10 +59,16 @@ func (r *BanyanDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	var banyanDB v1alpha1.BanyanDB
 	if err := r.Get(ctx, req.NamespacedName, &banyanDB); err != nil {
+		forgetMetrics("BanyanDB", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
@@ -68,33 +76,40 @@ func (r *BanyanDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	app := kubernetes.Application{
 		Client:   r.Client,
 		CR:       &banyanDB,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("BanyanDB"),
 		Recorder: r.Recorder,
 	}
 
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
-		return ctrl.Result{}, err
-	}
+	applyErr := app.ApplyAll(ctx, ff, log)
 
-	if err := r.checkState(ctx, log, &banyanDB); err != nil {
+	if err := r.checkState(ctx, log, &banyanDB, applyErr); err != nil {
 		log.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
 	return ctrl.Result{RequeueAfter: schedDuration}, nil
 }
 
-func (r *BanyanDBReconciler) checkState(ctx context.Context, log logr.Logger, banyanDB *operatorv1alpha1.BanyanDB) error {
-	overlay := operatorv1alpha1.BanyanDBStatus{}
+func (r *BanyanDBReconciler) checkState(ctx context.Context, log logr.Logger, banyanDB *operatorv1alpha1.BanyanDB, applyErr error) error {
+	overlay := operatorv1alpha1.BanyanDBStatus{
+		ObservedGeneration: banyanDB.Generation,
+		Inventory:          banyanDB.Status.Inventory,
+		TemplateOverrides:  banyanDB.Status.TemplateOverrides,
+	}
 	deployment := apps.Deployment{}
 	errCol := new(kubernetes.ErrorCollector)
 	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: banyanDB.Namespace, Name: banyanDB.Name + "-banyandb"}, &deployment); err != nil && !apierrors.IsNotFound(err) {
 		errCol.Collect(fmt.Errorf("failed to get deployment: %w", err))
 	} else {
-		overlay.Conditions = deployment.Status.Conditions
 		overlay.AvailableReplicas = deployment.Status.AvailableReplicas
 	}
+	overlay.Conditions = kubernetes.Conditions(banyanDB.Status.Conditions, banyanDB.Generation, applyErr,
+		overlay.AvailableReplicas, int32(banyanDB.Spec.Counts))
+	recordMetrics("BanyanDB", banyanDB, overlay.Conditions, applyErr)
 	if apiequal.Semantic.DeepDerivative(overlay, banyanDB.Status) {
 		log.Info("Status keeps the same as before")
 		return errCol.Error()
@@ -131,5 +146,6 @@ func (r *BanyanDBReconciler) updateStatus(ctx context.Context, banyanDB *operato
 func (r *BanyanDBReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.BanyanDB{}).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.BanyanDBList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
@@ -33,6 +33,7 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -63,16 +64,17 @@ func (r *EventExporterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	eventExporter := operatorv1alpha1.EventExporter{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &eventExporter); err != nil {
+		forgetMetrics("EventExporter", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	newConfigMapName := configMapName(&eventExporter)
-	if _, err := r.overlayData(ctx, log, &eventExporter, newConfigMapName); err != nil {
-		log.Error(err, "failed to delete eventexporter's configMap")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
-
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
@@ -80,7 +82,7 @@ func (r *EventExporterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       &eventExporter,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("EventExporter"),
 		Recorder: r.Recorder,
@@ -89,14 +91,27 @@ func (r *EventExporterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		},
 	}
 
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
+	if !eventExporter.DeletionTimestamp.IsZero() {
+		return finalize(ctx, log, &app)
+	}
+	if err := app.AddFinalizer(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if _, err := r.overlayData(ctx, log, &eventExporter, newConfigMapName); err != nil {
+		log.Error(err, "failed to delete eventexporter's configMap")
 		return ctrl.Result{}, err
 	}
 
-	if err := r.checkState(ctx, log, &eventExporter); err != nil {
+	applyErr := app.ApplyAll(ctx, ff, log)
+
+	if err := r.checkState(ctx, log, &eventExporter, applyErr); err != nil {
 		l.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
 	return ctrl.Result{RequeueAfter: schedDuration}, nil
 
@@ -156,8 +171,12 @@ func (r *EventExporterReconciler) overlayData(ctx context.Context, log logr.Logg
 	return true, nil
 }
 
-func (r *EventExporterReconciler) checkState(ctx context.Context, log logr.Logger, eventExporter *operatorv1alpha1.EventExporter) error {
-	overlay := operatorv1alpha1.EventExporterStatus{}
+func (r *EventExporterReconciler) checkState(ctx context.Context, log logr.Logger, eventExporter *operatorv1alpha1.EventExporter, applyErr error) error {
+	overlay := operatorv1alpha1.EventExporterStatus{
+		ObservedGeneration: eventExporter.Generation,
+		Inventory:          eventExporter.Status.Inventory,
+		TemplateOverrides:  eventExporter.Status.TemplateOverrides,
+	}
 	deployment := apps.Deployment{}
 	errCol := new(kubernetes.ErrorCollector)
 
@@ -165,11 +184,13 @@ func (r *EventExporterReconciler) checkState(ctx context.Context, log logr.Logge
 		Name: eventExporter.Name + "-eventexporter"}, &deployment); err != nil && !apierrors.IsNotFound(err) {
 		errCol.Collect(fmt.Errorf("failed to get deployment: %w", err))
 	} else {
-		overlay.Conditions = deployment.Status.Conditions
 		overlay.AvailableReplicas = deployment.Status.AvailableReplicas
 		overlay.ConfigMapName = configMapName(eventExporter)
 	}
 
+	overlay.Conditions = kubernetes.Conditions(eventExporter.Status.Conditions, eventExporter.Generation, applyErr,
+		overlay.AvailableReplicas, eventExporter.Spec.Replicas)
+	recordMetrics("EventExporter", eventExporter, overlay.Conditions, applyErr)
 	if apiequal.Semantic.DeepDerivative(overlay, eventExporter.Status) {
 		log.Info("Status keeps the same as before")
 		return errCol.Error()
@@ -214,5 +235,6 @@ func configMapName(eventExporter *operatorv1alpha1.EventExporter) string {
 func (r *EventExporterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.EventExporter{}).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.EventExporterList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
@@ -19,16 +19,18 @@ package operator
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiequal "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -47,6 +49,7 @@ type FetcherReconciler struct {
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=fetchers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=fetchers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=fetchers/finalizers,verbs=update
 
 func (r *FetcherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := runtimelog.FromContext(ctx)
@@ -54,65 +57,67 @@ func (r *FetcherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	fetcher := &operatorv1alpha1.Fetcher{}
 	if err := r.Client.Get(ctx, req.NamespacedName, fetcher); err != nil {
+		forgetMetrics("Fetcher", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       fetcher,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("Fetcher"),
 		Recorder: r.Recorder,
 	}
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
-		_ = r.UpdateStatus(ctx, fetcher, core.ConditionFalse, "Failed to apply resources")
+	if !fetcher.DeletionTimestamp.IsZero() {
+		return finalize(ctx, log, &app)
+	}
+	if err := app.AddFinalizer(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
-	if err := r.UpdateStatus(ctx, fetcher, core.ConditionTrue, "Reconciled all of resources"); err != nil {
+	applyErr := app.ApplyAll(ctx, ff, log)
+	if err := r.checkState(ctx, log, fetcher, applyErr); err != nil {
+		log.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 	return ctrl.Result{RequeueAfter: schedDuration}, nil
 }
 
-func (r *FetcherReconciler) UpdateStatus(ctx context.Context, fetcher *operatorv1alpha1.Fetcher, status core.ConditionStatus, msg string) error {
-	log := runtimelog.FromContext(ctx)
-
-	if fetcher.Status.Replicas == 0 {
-		fetcher.Status.Replicas = 1
+func (r *FetcherReconciler) checkState(ctx context.Context, log logr.Logger, fetcher *operatorv1alpha1.Fetcher, applyErr error) error {
+	overlay := operatorv1alpha1.FetcherStatus{
+		Replicas:           1,
+		ObservedGeneration: fetcher.Generation,
+		Inventory:          fetcher.Status.Inventory,
+		TemplateOverrides:  fetcher.Status.TemplateOverrides,
 	}
-	now := metav1.NewTime(time.Now())
-	changed := false
-	if len(fetcher.Status.Conditions) < 1 {
-		changed = true
-		fetcher.Status.Conditions = append(fetcher.Status.Conditions, operatorv1alpha1.FetcherCondition{
-			Type:               operatorv1alpha1.FetcherConditionTypeRead,
-			Status:             status,
-			Message:            msg,
-			LastTransitionTime: now,
-			LastUpdateTime:     now,
-		})
-	} else {
-		current := fetcher.Status.Conditions[0]
-		if current.Status != status || current.Message != msg {
-			changed = true
-			current.Status = status
-			current.Message = msg
-			current.LastUpdateTime = now
-		}
+	deployment := apps.Deployment{}
+	errCol := new(kubernetes.ErrorCollector)
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: fetcher.Namespace, Name: fetcher.Name + "-fetcher"}, &deployment); err != nil && !apierrors.IsNotFound(err) {
+		errCol.Collect(fmt.Errorf("failed to get deployment: %w", err))
 	}
-	if !changed {
-		return nil
+	overlay.Conditions = kubernetes.Conditions(fetcher.Status.Conditions, fetcher.Generation, applyErr,
+		deployment.Status.AvailableReplicas, overlay.Replicas)
+	recordMetrics("Fetcher", fetcher, overlay.Conditions, applyErr)
+	if apiequal.Semantic.DeepDerivative(overlay, fetcher.Status) {
+		log.Info("Status keeps the same as before")
+		return errCol.Error()
 	}
-	overlay := fetcher.Status
 	if err := r.updateStatus(ctx, fetcher, overlay, log); err != nil {
-		log.Error(err, "failed to update status")
-		return err
+		errCol.Collect(fmt.Errorf("failed to update status of fetcher: %w", err))
 	}
-	return nil
+	log.Info("updated Status sub resource")
+	return errCol.Error()
 }
 
 func (r *FetcherReconciler) updateStatus(ctx context.Context, fetcher *operatorv1alpha1.Fetcher,
@@ -139,5 +144,6 @@ func (r *FetcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&operatorv1alpha1.Fetcher{}).
 		Owns(&apps.Deployment{}).
 		Owns(&core.ConfigMap{}).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.FetcherList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
@@ -0,0 +1,46 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+)
+
+// finalizeDuration is the period to check whether the resources of a CR being deleted are gone
+var finalizeDuration, _ = time.ParseDuration("5s")
+
+// finalize cleans up the CR being deleted, which is requeued until the resources being deleted are gone
+func finalize(ctx context.Context, log logr.Logger, app *kubernetes.Application, unowned ...operatorv1alpha1.ResourceRef) (ctrl.Result, error) {
+	done, err := app.Finalize(ctx, unowned, log)
+	if err != nil {
+		log.Error(err, "failed to finalize")
+		return ctrl.Result{}, err
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: finalizeDuration}, nil
+	}
+	log.Info("finalized")
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,176 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// The indexes mapping the referenced objects back to the CRs referencing them
+const (
+	// oapServerStorageIndex indexes the OAPServers by the name of their Storage
+	oapServerStorageIndex = ".spec.storage.name"
+	// storageSecretIndex indexes the Storages by the name of their user Secret
+	storageSecretIndex = ".spec.security.user.secretName"
+	// satelliteOAPServerIndex indexes the Satellites by the name of their OAPServer
+	satelliteOAPServerIndex = ".spec.OAPServerName"
+	// uiOAPServerIndex indexes the UIs by the namespace/name of the OAPServer in their OAPServerAddress
+	uiOAPServerIndex = ".spec.OAPServerAddress"
+	// versionIndex indexes the OAPServerConfigs and OAPServerDynamicConfigs by their OAP version
+	versionIndex = ".spec.version"
+)
+
+// SetupIndexes registers the indexes used by the watches of the controllers, it must be called before they are set up.
+func SetupIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	for _, index := range []struct {
+		obj       client.Object
+		field     string
+		extractor client.IndexerFunc
+	}{
+		{&operatorv1alpha1.OAPServer{}, oapServerStorageIndex, storageOfOAPServer},
+		{&operatorv1alpha1.Storage{}, storageSecretIndex, userSecretOfStorage},
+		{&operatorv1alpha1.Satellite{}, satelliteOAPServerIndex, oapServerOfSatellite},
+		{&operatorv1alpha1.UI{}, uiOAPServerIndex, oapServerOfUI},
+		{&operatorv1alpha1.OAPServerConfig{}, versionIndex, versionOf},
+		{&operatorv1alpha1.OAPServerDynamicConfig{}, versionIndex, versionOf},
+	} {
+		if err := indexer.IndexField(ctx, index.obj, index.field, index.extractor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func storageOfOAPServer(o client.Object) []string {
+	oapServer := o.(*operatorv1alpha1.OAPServer)
+	if oapServer.Spec.StorageConfig == nil || oapServer.Spec.StorageConfig.Name == "" {
+		return nil
+	}
+	return []string{oapServer.Spec.StorageConfig.Name}
+}
+
+func userSecretOfStorage(o client.Object) []string {
+	name := o.(*operatorv1alpha1.Storage).Spec.Security.User.SecretName
+	// the default user doesn't refer to any Secret
+	if name == "" || name == "default" {
+		return nil
+	}
+	return []string{name}
+}
+
+func oapServerOfSatellite(o client.Object) []string {
+	name := o.(*operatorv1alpha1.Satellite).Spec.OAPServerName
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+func oapServerOfUI(o client.Object) []string {
+	ui := o.(*operatorv1alpha1.UI)
+	key := oapServerOfAddress(ui.Namespace, ui.Spec.OAPServerAddress)
+	if key == "" {
+		return nil
+	}
+	return []string{key}
+}
+
+// versionOf extracts the OAP version of the OAPServerConfigs and OAPServerDynamicConfigs
+func versionOf(o client.Object) []string {
+	switch config := o.(type) {
+	case *operatorv1alpha1.OAPServerConfig:
+		return []string{config.Spec.Version}
+	case *operatorv1alpha1.OAPServerDynamicConfig:
+		return []string{config.Spec.Version}
+	}
+	return nil
+}
+
+// oapServerOfAddress returns the namespace/name of the OAPServer whose service is the host of the address,
+// such as http://default-oap.skywalking-system:12800, or empty if the host isn't the service of an OAPServer.
+func oapServerOfAddress(namespace, address string) string {
+	if address == "" {
+		return ""
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	name, ok := strings.CutSuffix(labels[0], "-oap")
+	if !ok || name == "" {
+		return ""
+	}
+	if len(labels) > 1 {
+		namespace = labels[1]
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}.String()
+}
+
+// userSecretOfStorages filters the Secrets which are the user Secrets of the Storages in their namespaces, so the changes
+// of the other Secrets in the cluster are dropped before they're mapped to the CRs
+func userSecretOfStorages(c client.Reader) predicate.Funcs {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		list := &operatorv1alpha1.StorageList{}
+		if err := c.List(context.Background(), list, client.InNamespace(o.GetNamespace()),
+			client.MatchingFields{storageSecretIndex: o.GetName()}); err != nil {
+			// let the mapping report the error
+			return true
+		}
+		return len(list.Items) > 0
+	})
+}
+
+// requestsOf lists the objects matching the index in the namespace, and returns the requests to reconcile them.
+// All the objects in the namespace are listed if the index is empty.
+func requestsOf(ctx context.Context, c client.Reader, list client.ObjectList, namespace, index, value string) []reconcile.Request {
+	var opts []client.ListOption
+	if index != "" {
+		opts = append(opts, client.MatchingFields{index: value})
+	}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := c.List(ctx, list, opts...); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list the objects referencing", "index", index, "value", value)
+		return nil
+	}
+	var requests []reconcile.Request
+	_ = meta.EachListItem(list, func(o runtime.Object) error {
+		obj := o.(client.Object)
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		return nil
+	})
+	return requests
+}
@@ -0,0 +1,223 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// newIndexedClient serves the objects by a fake client with the indexes of the watches
+func newIndexedClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithIndex(&operatorv1alpha1.OAPServer{}, oapServerStorageIndex, storageOfOAPServer).
+		WithIndex(&operatorv1alpha1.Storage{}, storageSecretIndex, userSecretOfStorage).
+		WithIndex(&operatorv1alpha1.Satellite{}, satelliteOAPServerIndex, oapServerOfSatellite).
+		WithIndex(&operatorv1alpha1.UI{}, uiOAPServerIndex, oapServerOfUI).
+		WithIndex(&operatorv1alpha1.OAPServerConfig{}, versionIndex, versionOf).
+		Build()
+}
+
+func newOAPServer(namespace, name, storage string) *operatorv1alpha1.OAPServer {
+	oapServer := &operatorv1alpha1.OAPServer{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if storage != "" {
+		oapServer.Spec.StorageConfig = &operatorv1alpha1.RelevantStorage{Name: storage}
+	}
+	return oapServer
+}
+
+func newStorage(namespace, name, secret string) *operatorv1alpha1.Storage {
+	storage := &operatorv1alpha1.Storage{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	storage.Spec.Security.User.SecretName = secret
+	return storage
+}
+
+func TestIndexExtractors(t *testing.T) {
+	tests := []struct {
+		name      string
+		obj       client.Object
+		extractor client.IndexerFunc
+		want      []string
+	}{
+		{name: "the storage of the OAPServer", obj: newOAPServer("skywalking", "default", "es"), extractor: storageOfOAPServer, want: []string{"es"}},
+		{name: "the OAPServer without storage", obj: newOAPServer("skywalking", "default", ""), extractor: storageOfOAPServer},
+		{name: "the user Secret of the Storage", obj: newStorage("skywalking", "es", "es-user"), extractor: userSecretOfStorage, want: []string{"es-user"}},
+		{name: "the Storage of the default user", obj: newStorage("skywalking", "es", "default"), extractor: userSecretOfStorage},
+		{name: "the Storage without user", obj: newStorage("skywalking", "es", ""), extractor: userSecretOfStorage},
+		{
+			name:      "the OAPServer of the Satellite",
+			obj:       &operatorv1alpha1.Satellite{Spec: operatorv1alpha1.SatelliteSpec{OAPServerName: "default"}},
+			extractor: oapServerOfSatellite,
+			want:      []string{"default"},
+		},
+		{name: "the Satellite without OAPServer", obj: &operatorv1alpha1.Satellite{}, extractor: oapServerOfSatellite},
+		{
+			name: "the OAPServer in the namespace of the UI",
+			obj: &operatorv1alpha1.UI{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "skywalking"},
+				Spec:       operatorv1alpha1.UISpec{OAPServerAddress: "http://default-oap:12800"},
+			},
+			extractor: oapServerOfUI,
+			want:      []string{"skywalking/default"},
+		},
+		{
+			name: "the OAPServer in another namespace of the UI",
+			obj: &operatorv1alpha1.UI{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "skywalking"},
+				Spec:       operatorv1alpha1.UISpec{OAPServerAddress: "default-oap.observability.svc:12800"},
+			},
+			extractor: oapServerOfUI,
+			want:      []string{"observability/default"},
+		},
+		{
+			name: "the UI of an external OAP",
+			obj: &operatorv1alpha1.UI{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "skywalking"},
+				Spec:       operatorv1alpha1.UISpec{OAPServerAddress: "http://oap.example.com:12800"},
+			},
+			extractor: oapServerOfUI,
+		},
+		{
+			name:      "the version of the OAPServerConfig",
+			obj:       &operatorv1alpha1.OAPServerConfig{Spec: operatorv1alpha1.OAPServerConfigSpec{Version: "9.5.0"}},
+			extractor: versionOf,
+			want:      []string{"9.5.0"},
+		},
+		{
+			name:      "the version of the OAPServerDynamicConfig",
+			obj:       &operatorv1alpha1.OAPServerDynamicConfig{Spec: operatorv1alpha1.OAPServerDynamicConfigSpec{Version: "9.5.0"}},
+			extractor: versionOf,
+			want:      []string{"9.5.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.extractor(tt.obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserSecretOfStorages(t *testing.T) {
+	c := newIndexedClient(
+		newStorage("skywalking", "es", "es-user"),
+		newStorage("skywalking", "banyandb", "default"),
+	)
+	tests := []struct {
+		name      string
+		c         client.Reader
+		namespace string
+		secret    string
+		want      bool
+	}{
+		{name: "the user Secret of a Storage", c: c, namespace: "skywalking", secret: "es-user", want: true},
+		{name: "the Secret of the same name in another namespace", c: c, namespace: "default", secret: "es-user"},
+		{name: "the Secret referenced by no Storage", c: c, namespace: "skywalking", secret: "default-token"},
+		{
+			name: "the Secrets are passed to the mapping if the Storages can't be listed",
+			c:    fake.NewClientBuilder().WithScheme(c.Scheme()).Build(), namespace: "skywalking", secret: "default-token", want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: tt.namespace, Name: tt.secret}}
+			predicate := userSecretOfStorages(tt.c)
+			if got := predicate.Create(event.CreateEvent{Object: secret}); got != tt.want {
+				t.Errorf("Create() = %t, want %t", got, tt.want)
+			}
+			if got := predicate.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: secret}); got != tt.want {
+				t.Errorf("Update() = %t, want %t", got, tt.want)
+			}
+			if got := predicate.Delete(event.DeleteEvent{Object: secret}); got != tt.want {
+				t.Errorf("Delete() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// names returns the sorted namespace/name of the requests
+func names(requests []reconcile.Request) []string {
+	nn := make([]string, 0, len(requests))
+	for _, r := range requests {
+		nn = append(nn, r.String())
+	}
+	sort.Strings(nn)
+	return nn
+}
+
+func TestRequestsOf(t *testing.T) {
+	c := newIndexedClient(
+		newOAPServer("skywalking", "default", "es"),
+		newOAPServer("skywalking", "canary", "es"),
+		newOAPServer("skywalking", "banyandb", "banyandb"),
+		newOAPServer("observability", "default", "es"),
+	)
+	tests := []struct {
+		name      string
+		c         client.Reader
+		namespace string
+		index     string
+		value     string
+		want      []string
+	}{
+		{
+			name: "the objects referencing the value in the namespace", c: c, namespace: "skywalking", index: oapServerStorageIndex, value: "es",
+			want: []string{"skywalking/canary", "skywalking/default"},
+		},
+		{
+			name: "the objects referencing the value in all namespaces", c: c, index: oapServerStorageIndex, value: "es",
+			want: []string{"observability/default", "skywalking/canary", "skywalking/default"},
+		},
+		{name: "no objects reference the value", c: c, namespace: "skywalking", index: oapServerStorageIndex, value: "mysql"},
+		{
+			name: "all the objects in the namespace without the index", c: c, namespace: "skywalking",
+			want: []string{"skywalking/banyandb", "skywalking/canary", "skywalking/default"},
+		},
+		{
+			name: "failed to list the objects", c: fake.NewClientBuilder().WithScheme(c.Scheme()).Build(),
+			namespace: "skywalking", index: oapServerStorageIndex, value: "es",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requestsOf(context.Background(), tt.c, &operatorv1alpha1.OAPServerList{}, tt.namespace, tt.index, tt.value)
+			if got := names(got); len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("requestsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
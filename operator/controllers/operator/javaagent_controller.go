@@ -116,9 +116,14 @@ func (r *JavaAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	selectorname := strings.Join([]string{keys[0], labels[keys[0]]}, "-")
 	podselector := strings.Join([]string{keys[0], labels[keys[0]]}, "=")
 
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       pod,
 		GVK:      core.SchemeGroupVersion.WithKind("Pod"),
 		TmplFunc: map[string]interface{}{
@@ -249,6 +254,9 @@ func (r *JavaAgentReconciler) UpdateStatus(ctx context.Context, log logr.Logger,
 
 	javaagent.Status.ExpectedInjectedNum = expectedInjectedNum
 	javaagent.Status.RealInjectedNum = realInjectedNum
+	javaagent.Status.ObservedGeneration = javaagent.Generation
+	javaagent.Status.Conditions = kubernetes.StateConditions(javaagent.Status.Conditions, javaagent.Generation, nil,
+		realInjectedNum >= expectedInjectedNum, fmt.Sprintf("%d/%d pods are injected", realInjectedNum, expectedInjectedNum))
 
 	nilTime := metav1.Time{}
 	now := metav1.NewTime(time.Now())
@@ -0,0 +1,42 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/metrics"
+)
+
+// recordMetrics records the readiness of the CR, and the time of its reconciliation if all of its resources are applied
+func recordMetrics(kind string, cr client.Object, conditions []metav1.Condition, applyErr error) {
+	metrics.RecordReconciled(kind, cr.GetNamespace(), cr.GetName(),
+		meta.IsStatusConditionTrue(conditions, operatorv1alpha1.ConditionTypeReady), applyErr == nil)
+}
+
+// forgetMetrics deletes the metrics of the CR if it's not found, which means it has been deleted
+func forgetMetrics(kind string, req ctrl.Request, err error) {
+	if apierrors.IsNotFound(err) {
+		metrics.Forget(kind, req.Namespace, req.Name)
+	}
+}
@@ -33,15 +33,23 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
 	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
 )
 
+// schedDuration is the period of the resync of the CRs, whose referenced objects are not watched
 var schedDuration, _ = time.ParseDuration("1m")
 
+// slowSchedDuration is the period of the slow resync of the CRs, the changes of whose referenced objects
+// are picked up by the watches
+var slowSchedDuration, _ = time.ParseDuration("10m")
+
 // OAPServerReconciler reconciles a OAPServer object
 type OAPServerReconciler struct {
 	client.Client
@@ -52,6 +60,7 @@ type OAPServerReconciler struct {
 
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=oapservers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=oapservers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=oapservers/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services;serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
@@ -66,43 +75,60 @@ func (r *OAPServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	oapServer := operatorv1alpha1.OAPServer{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &oapServer); err != nil {
+		forgetMetrics("OAPServer", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       &oapServer,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("OAPServer"),
 		Recorder: r.Recorder,
 	}
 
-	r.InjectStorage(ctx, log, &oapServer)
-
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
+	if !oapServer.DeletionTimestamp.IsZero() {
+		return finalize(ctx, log, &app)
+	}
+	if err := app.AddFinalizer(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.checkState(ctx, log, &oapServer); err != nil {
+	r.InjectStorage(ctx, log, &oapServer)
+
+	applyErr := app.ApplyAll(ctx, ff, log)
+
+	if err := r.checkState(ctx, log, &oapServer, applyErr); err != nil {
 		l.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
 }
 
-func (r *OAPServerReconciler) checkState(ctx context.Context, log logr.Logger, oapServer *operatorv1alpha1.OAPServer) error {
-	overlay := operatorv1alpha1.OAPServerStatus{}
+func (r *OAPServerReconciler) checkState(ctx context.Context, log logr.Logger, oapServer *operatorv1alpha1.OAPServer, applyErr error) error {
+	overlay := operatorv1alpha1.OAPServerStatus{
+		ObservedGeneration: oapServer.Generation,
+		Inventory:          oapServer.Status.Inventory,
+		TemplateOverrides:  oapServer.Status.TemplateOverrides,
+	}
 	deployment := apps.Deployment{}
 	errCol := new(kubernetes.ErrorCollector)
 	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: oapServer.Namespace, Name: oapServer.Name + "-oap"}, &deployment); err != nil && !apierrors.IsNotFound(err) {
 		errCol.Collect(fmt.Errorf("failed to get deployment: %w", err))
 	} else {
-		overlay.Conditions = deployment.Status.Conditions
 		overlay.AvailableReplicas = deployment.Status.AvailableReplicas
 	}
 	service := core.Service{}
@@ -111,6 +137,9 @@ func (r *OAPServerReconciler) checkState(ctx context.Context, log logr.Logger, o
 	} else {
 		overlay.Address = fmt.Sprintf("%s.%s", service.Name, service.Namespace)
 	}
+	overlay.Conditions = kubernetes.Conditions(oapServer.Status.Conditions, oapServer.Generation, applyErr,
+		overlay.AvailableReplicas, oapServer.Spec.Instances)
+	recordMetrics("OAPServer", oapServer, overlay.Conditions, applyErr)
 	if apiequal.Semantic.DeepDerivative(overlay, oapServer.Status) {
 		log.Info("Status keeps the same as before")
 		return errCol.Error()
@@ -221,5 +250,23 @@ func (r *OAPServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&operatorv1alpha1.OAPServer{}).
 		Owns(&apps.Deployment{}).
 		Owns(&core.Service{}).
+		Watches(&operatorv1alpha1.Storage{}, handler.EnqueueRequestsFromMapFunc(r.oapServersOfStorage)).
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(r.oapServersOfSecret),
+			builder.WithPredicates(userSecretOfStorages(r.Client))).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.OAPServerList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
+
+// oapServersOfStorage maps the Storage to the OAPServers using it
+func (r *OAPServerReconciler) oapServersOfStorage(ctx context.Context, o client.Object) []reconcile.Request {
+	return requestsOf(ctx, r.Client, &operatorv1alpha1.OAPServerList{}, o.GetNamespace(), oapServerStorageIndex, o.GetName())
+}
+
+// oapServersOfSecret maps the user Secret of Storages to the OAPServers using them
+func (r *OAPServerReconciler) oapServersOfSecret(ctx context.Context, o client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, s := range requestsOf(ctx, r.Client, &operatorv1alpha1.StorageList{}, o.GetNamespace(), storageSecretIndex, o.GetName()) {
+		requests = append(requests, requestsOf(ctx, r.Client, &operatorv1alpha1.OAPServerList{}, s.Namespace, oapServerStorageIndex, s.Name)...)
+	}
+	return requests
+}
@@ -32,7 +32,9 @@ import (
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
 	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
@@ -93,6 +95,7 @@ func (r *OAPServerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, fmt.Errorf("failed to list oapserver: %w", err)
 	}
 
+	applyErrs := new(kubernetes.ErrorCollector)
 	// get the specific version's oapserver
 	for i := range oapList.Items {
 		if oapList.Items[i].Spec.Version == oapServerConfig.Spec.Version {
@@ -105,27 +108,33 @@ func (r *OAPServerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			envChanged, err := r.OverlayEnv(log, &oapServerConfig, &deployment)
 			if err != nil {
 				log.Error(err, "failed to overlay the env configuration")
+				applyErrs.Collect(err)
 			}
 			// overlay the file configuration
 			fileChanged, err := r.OverlayStaticFile(ctx, log, &oapServerConfig, &deployment)
 			if err != nil {
 				log.Error(err, "failed to overlay the file configuration")
+				applyErrs.Collect(err)
 			}
 			// update the deployment
 			if envChanged || fileChanged {
 				if err := r.Client.Update(ctx, &deployment); err != nil {
-					return ctrl.Result{}, fmt.Errorf("failed to update the deployment of OAPServer: %w", err)
+					applyErrs.Collect(fmt.Errorf("failed to update the deployment of OAPServer: %w", err))
 				}
 			}
 		}
 	}
 
-	if err := r.checkState(ctx, log, &oapServerConfig, oapList); err != nil {
+	applyErr := applyErrs.Error()
+	if err := r.checkState(ctx, log, &oapServerConfig, oapList, applyErr); err != nil {
 		log.Error(err, "failed to update OAPServerConfig's status")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
 }
 
 func (r *OAPServerConfigReconciler) OverlayEnv(log logr.Logger,
@@ -242,12 +251,14 @@ func (r *OAPServerConfigReconciler) OverlayStaticFile(ctx context.Context, log l
 }
 
 func (r *OAPServerConfigReconciler) checkState(ctx context.Context, log logr.Logger,
-	oapServerConfig *operatorv1alpha1.OAPServerConfig, oapList operatorv1alpha1.OAPServerList) error {
+	oapServerConfig *operatorv1alpha1.OAPServerConfig, oapList operatorv1alpha1.OAPServerList, applyErr error) error {
 	errCol := new(kubernetes.ErrorCollector)
 
 	nilTime := metav1.Time{}
 	now := metav1.NewTime(time.Now())
-	overlay := operatorv1alpha1.OAPServerConfigStatus{}
+	overlay := operatorv1alpha1.OAPServerConfigStatus{
+		ObservedGeneration: oapServerConfig.Generation,
+	}
 
 	// get Instances and AvailableReplicas
 	for i := range oapList.Items {
@@ -256,6 +267,8 @@ func (r *OAPServerConfigReconciler) checkState(ctx context.Context, log logr.Log
 			overlay.Ready += int(oapList.Items[i].Status.AvailableReplicas)
 		}
 	}
+	overlay.Conditions = kubernetes.Conditions(oapServerConfig.Status.Conditions, oapServerConfig.Generation, applyErr,
+		int32(overlay.Ready), int32(overlay.Desired))
 
 	if oapServerConfig.Status.CreationTime == nilTime {
 		overlay.CreationTime = now
@@ -304,5 +317,9 @@ func (r *OAPServerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.OAPServerConfig{}).
 		Owns(&apps.Deployment{}).
+		Watches(&operatorv1alpha1.OAPServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			version := o.(*operatorv1alpha1.OAPServer).Spec.Version
+			return requestsOf(ctx, r.Client, &operatorv1alpha1.OAPServerConfigList{}, o.GetNamespace(), versionIndex, version)
+		})).
 		Complete(r)
 }
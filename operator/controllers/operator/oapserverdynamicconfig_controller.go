@@ -34,8 +34,10 @@ import (
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
 	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
@@ -86,26 +88,29 @@ func (r *OAPServerDynamicConfigReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, fmt.Errorf("failed to list oapserver: %w", err)
 	}
 
+	var applyErr error
 	// get the specific version's oapserver
 	for i := range oapList.Items {
 		if oapList.Items[i].Spec.Version == config.Spec.Version {
 			oapServer := oapList.Items[i]
 			// Update the dynamic configuration
-			err := r.UpdateDynamicConfig(ctx, log, &oapServer, &config)
-			if err != nil {
-				log.Error(err, "failed to update the dynamic configuration")
-				return ctrl.Result{}, err
+			if applyErr = r.UpdateDynamicConfig(ctx, log, &oapServer, &config); applyErr != nil {
+				log.Error(applyErr, "failed to update the dynamic configuration")
+				break
 			}
 
 		}
 	}
 
-	if err := r.checkState(ctx, log, &config); err != nil {
+	if err := r.checkState(ctx, log, &config, applyErr); err != nil {
 		log.Error(err, "failed to update OAPServerDynamicConfig's status")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
 }
 
 func (r *OAPServerDynamicConfigReconciler) UpdateDynamicConfig(ctx context.Context, log logr.Logger,
@@ -217,13 +222,14 @@ func (r *OAPServerDynamicConfigReconciler) UpdateDynamicConfig(ctx context.Conte
 }
 
 func (r *OAPServerDynamicConfigReconciler) checkState(ctx context.Context, log logr.Logger,
-	config *operatorv1alpha1.OAPServerDynamicConfig) error {
+	config *operatorv1alpha1.OAPServerDynamicConfig, applyErr error) error {
 	errCol := new(kubernetes.ErrorCollector)
 
 	nilTime := metav1.Time{}
 	now := metav1.NewTime(time.Now())
 	overlay := operatorv1alpha1.OAPServerDynamicConfigStatus{
-		State: "Stopped",
+		State:              "Stopped",
+		ObservedGeneration: config.Generation,
 	}
 
 	// get dynamic configuration's state
@@ -245,6 +251,8 @@ func (r *OAPServerDynamicConfigReconciler) checkState(ctx context.Context, log l
 			overlay.State = "Running"
 		}
 	}
+	overlay.Conditions = kubernetes.StateConditions(config.Status.Conditions, config.Generation, applyErr,
+		overlay.State == "Running", fmt.Sprintf("the dynamic configuration is %s", strings.ToLower(overlay.State)))
 
 	if config.Status.CreationTime == nilTime {
 		overlay.CreationTime = now
@@ -292,6 +300,10 @@ func (r *OAPServerDynamicConfigReconciler) updateStatus(ctx context.Context, con
 func (r *OAPServerDynamicConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.OAPServerDynamicConfig{}).
+		Watches(&operatorv1alpha1.OAPServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			version := o.(*operatorv1alpha1.OAPServer).Spec.Version
+			return requestsOf(ctx, r.Client, &operatorv1alpha1.OAPServerDynamicConfigList{}, o.GetNamespace(), versionIndex, version)
+		})).
 		Complete(r)
 }
 
@@ -29,8 +29,11 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
 	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
@@ -54,41 +57,58 @@ func (r *SatelliteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	satellite := operatorv1alpha1.Satellite{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &satellite); err != nil {
+		forgetMetrics("Satellite", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       &satellite,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("Satellite"),
 		Recorder: r.Recorder,
 	}
 
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
+	if !satellite.DeletionTimestamp.IsZero() {
+		return finalize(ctx, log, &app)
+	}
+	if err := app.AddFinalizer(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.checkState(ctx, log, &satellite); err != nil {
+	applyErr := app.ApplyAll(ctx, ff, log)
+
+	if err := r.checkState(ctx, log, &satellite, applyErr); err != nil {
 		log.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
 }
 
-func (r *SatelliteReconciler) checkState(ctx context.Context, log logr.Logger, satellite *operatorv1alpha1.Satellite) error {
-	overlay := operatorv1alpha1.SatelliteStatus{}
+func (r *SatelliteReconciler) checkState(ctx context.Context, log logr.Logger, satellite *operatorv1alpha1.Satellite, applyErr error) error {
+	overlay := operatorv1alpha1.SatelliteStatus{
+		ObservedGeneration: satellite.Generation,
+		Inventory:          satellite.Status.Inventory,
+		TemplateOverrides:  satellite.Status.TemplateOverrides,
+	}
 	deployment := apps.Deployment{}
 	errCol := new(kubernetes.ErrorCollector)
 	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: satellite.Namespace, Name: satellite.Name + "-satellite"}, &deployment); err != nil {
 		errCol.Collect(fmt.Errorf("failed to get deployment :%v", err))
 	} else {
-		overlay.Conditions = deployment.Status.Conditions
 		overlay.AvailableReplicas = deployment.Status.AvailableReplicas
 	}
 	service := core.Service{}
@@ -97,6 +117,9 @@ func (r *SatelliteReconciler) checkState(ctx context.Context, log logr.Logger, s
 	} else {
 		overlay.Address = fmt.Sprintf("%s.%s", service.Name, service.Namespace)
 	}
+	overlay.Conditions = kubernetes.Conditions(satellite.Status.Conditions, satellite.Generation, applyErr,
+		overlay.AvailableReplicas, satellite.Spec.Instances)
+	recordMetrics("Satellite", satellite, overlay.Conditions, applyErr)
 	if apiequal.Semantic.DeepDerivative(overlay, satellite.Status) {
 		log.Info("Status keeps the same as before")
 		return errCol.Error()
@@ -138,5 +161,9 @@ func (r *SatelliteReconciler) updateStatus(ctx context.Context, satellite *opera
 func (r *SatelliteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.Satellite{}).
+		Watches(&operatorv1alpha1.OAPServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			return requestsOf(ctx, r.Client, &operatorv1alpha1.SatelliteList{}, o.GetNamespace(), satelliteOAPServerIndex, o.GetName())
+		})).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.SatelliteList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
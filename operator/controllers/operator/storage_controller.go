@@ -44,8 +44,11 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	pkcs12 "software.sslmate.com/src/go-pkcs12"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
@@ -63,6 +66,7 @@ type StorageReconciler struct {
 
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=storages,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=storages/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=storages/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services;serviceaccounts;secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests,verbs=get;list;watch;create;delete
@@ -74,37 +78,82 @@ func (r *StorageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	storage := operatorv1alpha1.Storage{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &storage); err != nil {
+		forgetMetrics("Storage", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	if storage.Spec.ConnectType == "external" {
-		return ctrl.Result{RequeueAfter: schedDuration}, nil
-	}
-
-	r.createCert(ctx, log, &storage)
-	r.checkSecurity(ctx, log, &storage)
-
-	ff, err := r.FileRepo.GetFilesRecursive(storage.Spec.Type + "/templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       &storage,
 		GVK:      operatorv1alpha1.GroupVersion.WithKind("Storage"),
 		Recorder: r.Recorder,
 		TmplFunc: tmplFunc(),
 	}
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
+	if !storage.DeletionTimestamp.IsZero() {
+		unowned, err := r.unowned(ctx, &storage)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return finalize(ctx, log, &app, unowned...)
+	}
+	if storage.Spec.ConnectType == "external" {
+		return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
+	}
+	if err := app.AddFinalizer(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.createCert(ctx, log, &storage)
+	r.checkSecurity(ctx, log, &storage)
+
+	ff, err := repo.GetFilesRecursive(storage.Spec.Type + "/templates")
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
-	if err := r.checkState(ctx, log, &storage); err != nil {
+	applyErr := app.ApplyAll(ctx, ff, log)
+	if err := r.checkState(ctx, log, &storage, applyErr); err != nil {
 		log.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
+}
+
+// unowned returns the certificate Secret and the CertificateSigningRequest created for the Storage without the owner
+// references, unless they are shared with other Storages in the namespace and in the cluster respectively
+func (r *StorageReconciler) unowned(ctx context.Context, storage *operatorv1alpha1.Storage) ([]operatorv1alpha1.ResourceRef, error) {
+	storages := operatorv1alpha1.StorageList{}
+	if err := r.Client.List(ctx, &storages); err != nil {
+		return nil, fmt.Errorf("failed to list storages: %w", err)
+	}
+	sharedInNamespace, sharedInCluster := false, false
+	for i := range storages.Items {
+		s := &storages.Items[i]
+		if s.UID == storage.UID || !s.DeletionTimestamp.IsZero() || s.Spec.ConnectType == "external" {
+			continue
+		}
+		sharedInCluster = true
+		if s.Namespace == storage.Namespace {
+			sharedInNamespace = true
+		}
+	}
+	var refs []operatorv1alpha1.ResourceRef
+	if !sharedInNamespace {
+		refs = append(refs, operatorv1alpha1.ResourceRef{APIVersion: "v1", Kind: "Secret", Namespace: storage.Namespace, Name: "skywalking-storage"})
+	}
+	if !sharedInCluster {
+		refs = append(refs, operatorv1alpha1.ResourceRef{APIVersion: "certificates.k8s.io/v1", Kind: "CertificateSigningRequest", Name: "storage-csr"})
+	}
+	return refs, nil
 }
 
 func tmplFunc() map[string]interface{} {
@@ -118,23 +167,21 @@ func getProtocol(tls bool) string {
 	return "http"
 }
 
-func (r *StorageReconciler) checkState(ctx context.Context, log logr.Logger, storage *operatorv1alpha1.Storage) error {
-	overlay := operatorv1alpha1.StorageStatus{}
+func (r *StorageReconciler) checkState(ctx context.Context, log logr.Logger, storage *operatorv1alpha1.Storage, applyErr error) error {
+	overlay := operatorv1alpha1.StorageStatus{
+		ObservedGeneration: storage.Generation,
+		Inventory:          storage.Status.Inventory,
+		TemplateOverrides:  storage.Status.TemplateOverrides,
+	}
 	statefulset := apps.StatefulSet{}
 	errCol := new(kubernetes.ErrorCollector)
 	object := client.ObjectKey{Namespace: storage.Namespace, Name: storage.Name + "-" + storage.Spec.Type}
 	if err := r.Client.Get(ctx, object, &statefulset); err != nil && !apierrors.IsNotFound(err) {
 		errCol.Collect(fmt.Errorf("failed to get statefulset: %w", err))
-	} else {
-		if statefulset.Status.ReadyReplicas == statefulset.Status.Replicas {
-			overlay.Conditions = append(overlay.Conditions, apps.StatefulSetCondition{
-				Type:               "Ready",
-				Status:             "True",
-				LastTransitionTime: metav1.NewTime(time.Now()),
-				Reason:             "statefulset " + object.Name + " is ready",
-			})
-		}
 	}
+	overlay.Conditions = kubernetes.Conditions(storage.Status.Conditions, storage.Generation, applyErr,
+		statefulset.Status.ReadyReplicas, storage.Spec.Instances)
+	recordMetrics("Storage", storage, overlay.Conditions, applyErr)
 
 	if apiequal.Semantic.DeepDerivative(overlay, storage.Status) {
 		log.Info("Status keeps the same as before")
@@ -366,5 +413,9 @@ func (r *StorageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.Storage{}).
 		Owns(&core.Service{}).
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			return requestsOf(ctx, r.Client, &operatorv1alpha1.StorageList{}, o.GetNamespace(), storageSecretIndex, o.GetName())
+		}), builder.WithPredicates(userSecretOfStorages(r.Client))).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &operatorv1alpha1.StorageList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
@@ -19,13 +19,16 @@ package operator
 
 import (
 	"context"
+	"fmt"
 
+	apiequal "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
 )
 
 // SwAgentReconciler reconciles a SwAgent object
@@ -38,9 +41,29 @@ type SwAgentReconciler struct {
 //+kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=swagents/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=operator.skywalking.apache.org,resources=swagents/finalizers,verbs=update
 
-func (r *SwAgentReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+func (r *SwAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := runtimelog.FromContext(ctx)
-	log.Info("===================== SwAgent Reconcile (do nothing) ================================\n")
+	log.Info("===================== SwAgent Reconcile ================================\n")
+
+	swAgent := operatorv1alpha1.SwAgent{}
+	if err := r.Client.Get(ctx, req.NamespacedName, &swAgent); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// the SwAgent is only read by the injector, so it is ready as soon as it is observed
+	overlay := operatorv1alpha1.SwAgentStatus{
+		ObservedGeneration: swAgent.Generation,
+		Conditions:         kubernetes.StateConditions(swAgent.Status.Conditions, swAgent.Generation, nil, true, "observed by the injector"),
+	}
+	if apiequal.Semantic.DeepDerivative(overlay, swAgent.Status) {
+		log.Info("Status keeps the same as before")
+		return ctrl.Result{}, nil
+	}
+	swAgent.Status.ObservedGeneration = overlay.ObservedGeneration
+	swAgent.Status.Conditions = overlay.Conditions
+	if err := r.Status().Update(ctx, &swAgent); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status of SwAgent: %w", err)
+	}
+	log.Info("updated Status sub resource")
 	return ctrl.Result{}, nil
 }
 
@@ -0,0 +1,54 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+	"github.com/apache/skywalking-swck/operator/pkg/operator/manifests"
+)
+
+// templatesChanged filters the ConfigMaps overriding the templates of the repo, which are labeled with its component
+// in the namespace of the overrides. A ConfigMap losing the label is taken as well, since its templates are removed.
+func templatesChanged(repo kubernetes.Repo) predicate.Funcs {
+	overlay, ok := repo.(*manifests.OverlayRepo)
+	overrides := func(o client.Object) bool {
+		return ok && overlay.Namespace != "" && o.GetNamespace() == overlay.Namespace &&
+			o.GetLabels()[manifests.TemplatesLabel] == overlay.Root
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return overrides(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return overrides(e.ObjectOld) || overrides(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return overrides(e.Object) },
+		GenericFunc: func(e event.GenericEvent) bool { return overrides(e.Object) },
+	}
+}
+
+// requestsOfAll reconciles all the objects of the list, whose templates are overridden by the changed ConfigMap
+func requestsOfAll(c client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		return requestsOf(ctx, c, list.DeepCopyObject().(client.ObjectList), "", "", "")
+	})
+}
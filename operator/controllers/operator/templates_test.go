@@ -0,0 +1,110 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package operator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+	"github.com/apache/skywalking-swck/operator/pkg/operator/manifests"
+)
+
+func TestTemplatesChanged(t *testing.T) {
+	overrides := func(namespace string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "templates", Labels: labels}}
+	}
+	labeled := map[string]string{manifests.TemplatesLabel: "oapserver"}
+	overlay := manifests.NewOverlayRepo("oapserver", "", nil, "skywalking-system")
+	tests := []struct {
+		name          string
+		repo          kubernetes.Repo
+		before, after *corev1.ConfigMap
+		want          bool
+	}{
+		{name: "the ConfigMap of the component", repo: overlay, after: overrides("skywalking-system", labeled), want: true},
+		{
+			name: "the ConfigMap of another component", repo: overlay,
+			after: overrides("skywalking-system", map[string]string{manifests.TemplatesLabel: "ui"}),
+		},
+		{name: "the ConfigMap in another namespace", repo: overlay, after: overrides("default", labeled)},
+		{
+			name: "the ConfigMap losing the label", repo: overlay,
+			before: overrides("skywalking-system", labeled), after: overrides("skywalking-system", nil), want: true,
+		},
+		{
+			name: "the overrides are disabled", repo: manifests.NewOverlayRepo("oapserver", "", nil, ""),
+			after: overrides("skywalking-system", labeled),
+		},
+		{name: "the templates are not overridable", repo: manifests.NewRepo("oapserver"), after: overrides("skywalking-system", labeled)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicate := templatesChanged(tt.repo)
+			if tt.before == nil {
+				if got := predicate.Create(event.CreateEvent{Object: tt.after}); got != tt.want {
+					t.Errorf("Create() = %t, want %t", got, tt.want)
+				}
+				if got := predicate.Delete(event.DeleteEvent{Object: tt.after}); got != tt.want {
+					t.Errorf("Delete() = %t, want %t", got, tt.want)
+				}
+				tt.before = tt.after
+			}
+			if got := predicate.Update(event.UpdateEvent{ObjectOld: tt.before, ObjectNew: tt.after}); got != tt.want {
+				t.Errorf("Update() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestsOfAll(t *testing.T) {
+	c := newIndexedClient(
+		newOAPServer("skywalking", "default", "es"),
+		newOAPServer("observability", "default", ""),
+	)
+	list := &operatorv1alpha1.OAPServerList{}
+	h := requestsOfAll(c, list)
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "skywalking-system", Name: "templates"}}
+	// the requests of the repeated events are merged by the queue
+	for i := 0; i < 2; i++ {
+		h.Create(context.Background(), event.CreateEvent{Object: cm}, queue)
+	}
+	var got []reconcile.Request
+	for queue.Len() > 0 {
+		r, _ := queue.Get()
+		got = append(got, r)
+		queue.Done(r)
+	}
+	if want := []string{"observability/default", "skywalking/default"}; !reflect.DeepEqual(names(got), want) {
+		t.Errorf("requestsOfAll() = %v, want %v", names(got), want)
+	}
+	// the list is copied for each event
+	if len(list.Items) != 0 {
+		t.Errorf("the list of the handler is filled: %d items", len(list.Items))
+	}
+}
@@ -31,8 +31,11 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	runtimelog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
 	uiv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
@@ -59,39 +62,49 @@ func (r *UIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Re
 
 	ui := uiv1alpha1.UI{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &ui); err != nil {
+		forgetMetrics("UI", req, err)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	ff, err := r.FileRepo.GetFilesRecursive("templates")
+	repo, err := kubernetes.Snapshot(ctx, r.FileRepo)
+	if err != nil {
+		log.Error(err, "failed to load resource templates")
+		return ctrl.Result{}, err
+	}
+	ff, err := repo.GetFilesRecursive("templates")
 	if err != nil {
 		log.Error(err, "failed to load resource templates")
 		return ctrl.Result{}, err
 	}
 	app := kubernetes.Application{
 		Client:   r.Client,
-		FileRepo: r.FileRepo,
+		FileRepo: repo,
 		CR:       &ui,
 		GVK:      uiv1alpha1.GroupVersion.WithKind("UI"),
 		Recorder: r.Recorder,
 	}
-	if err := app.ApplyAll(ctx, ff, log); err != nil {
-		return ctrl.Result{}, err
-	}
-	if err := r.checkState(ctx, log, &ui); err != nil {
+	applyErr := app.ApplyAll(ctx, ff, log)
+	if err := r.checkState(ctx, log, &ui, applyErr); err != nil {
 		log.Error(err, "failed to check sub resources state")
 		return ctrl.Result{}, err
 	}
+	if applyErr != nil {
+		return ctrl.Result{}, applyErr
+	}
 
-	return ctrl.Result{RequeueAfter: schedDuration}, nil
+	return ctrl.Result{RequeueAfter: slowSchedDuration}, nil
 }
 
-func (r *UIReconciler) checkState(ctx context.Context, log logr.Logger, ui *uiv1alpha1.UI) error {
-	overlay := uiv1alpha1.UIStatus{}
+func (r *UIReconciler) checkState(ctx context.Context, log logr.Logger, ui *uiv1alpha1.UI, applyErr error) error {
+	overlay := uiv1alpha1.UIStatus{
+		ObservedGeneration: ui.Generation,
+		Inventory:          ui.Status.Inventory,
+		TemplateOverrides:  ui.Status.TemplateOverrides,
+	}
 	deployment := apps.Deployment{}
 	errCol := new(kubernetes.ErrorCollector)
 	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: ui.Namespace, Name: ui.Name + "-ui"}, &deployment); err != nil && !apierrors.IsNotFound(err) {
 		errCol.Collect(fmt.Errorf("failed to get deployment: %w", err))
 	} else {
-		overlay.Conditions = deployment.Status.Conditions
 		overlay.AvailableReplicas = deployment.Status.AvailableReplicas
 	}
 	svc := core.Service{}
@@ -109,6 +122,9 @@ func (r *UIReconciler) checkState(ctx context.Context, log logr.Logger, ui *uiv1
 		}
 		overlay.InternalAddress = fmt.Sprintf("%s.%s", svc.Name, svc.Namespace)
 	}
+	overlay.Conditions = kubernetes.Conditions(ui.Status.Conditions, ui.Generation, applyErr,
+		overlay.AvailableReplicas, ui.Spec.Instances)
+	recordMetrics("UI", ui, overlay.Conditions, applyErr)
 	if apiequal.Semantic.DeepDerivative(overlay, ui.Status) {
 		log.Info("Status keeps the same as before")
 		return errCol.Error()
@@ -153,5 +169,10 @@ func (r *UIReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&core.Service{}).
 		Owns(&core.ConfigMap{}).
 		Owns(&networkingv1.Ingress{}).
+		Watches(&operatorv1alpha1.OAPServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			// the UIs may be in other namespaces than the OAPServer
+			return requestsOf(ctx, r.Client, &uiv1alpha1.UIList{}, "", uiOAPServerIndex, client.ObjectKeyFromObject(o).String())
+		})).
+		Watches(&core.ConfigMap{}, requestsOfAll(r.Client, &uiv1alpha1.UIList{}), builder.WithPredicates(templatesChanged(r.FileRepo))).
 		Complete(r)
 }
@@ -18,6 +18,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
@@ -84,10 +85,18 @@ func main() {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	newRepo := func(component string) *manifests.OverlayRepo {
+		return manifests.NewOverlayRepo(component, options.Templates.Directory, mgr.GetClient(),
+			options.Templates.ConfigMapNamespace)
+	}
+	if err = operatorcontroller.SetupIndexes(context.Background(), mgr); err != nil {
+		setupLog.Error(err, "unable to set up the field indexes")
+		os.Exit(1)
+	}
 	if err = (&operatorcontroller.OAPServerReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("oapserver"),
+		FileRepo: newRepo("oapserver"),
 		Recorder: mgr.GetEventRecorder("oapserver-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "OAPServer")
@@ -96,7 +105,7 @@ func main() {
 	if err = (&operatorcontroller.UIReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("ui"),
+		FileRepo: newRepo("ui"),
 		Recorder: mgr.GetEventRecorder("ui-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "UI")
@@ -105,7 +114,7 @@ func main() {
 	if err = (&operatorcontroller.FetcherReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("fetcher"),
+		FileRepo: newRepo("fetcher"),
 		Recorder: mgr.GetEventRecorder("fetcher-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Fetcher")
@@ -114,7 +123,7 @@ func main() {
 	if err = (&operatorcontroller.StorageReconciler{
 		Client:     mgr.GetClient(),
 		Scheme:     mgr.GetScheme(),
-		FileRepo:   manifests.NewRepo("storage"),
+		FileRepo:   newRepo("storage"),
 		RestConfig: mgr.GetConfig(),
 		Recorder:   mgr.GetEventRecorder("storage-controller"),
 	}).SetupWithManager(mgr); err != nil {
@@ -124,7 +133,7 @@ func main() {
 	if err = (&operatorcontroller.JavaAgentReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("injector"),
+		FileRepo: newRepo("injector"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JavaAgent")
 		os.Exit(1)
@@ -133,7 +142,7 @@ func main() {
 	if err = (&operatorcontrollers.SatelliteReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("satellite"),
+		FileRepo: newRepo("satellite"),
 		Recorder: mgr.GetEventRecorder("satellite-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Satellite")
@@ -164,7 +173,7 @@ func main() {
 	if err = (&operatorcontrollers.BanyanDBReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("banyandb"),
+		FileRepo: newRepo("banyandb"),
 		Recorder: mgr.GetEventRecorder("banyandb-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BanyanDB")
@@ -173,7 +182,7 @@ func main() {
 	if err = (&operatorcontrollers.EventExporterReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		FileRepo: manifests.NewRepo("eventexporter"),
+		FileRepo: newRepo("eventexporter"),
 		Recorder: mgr.GetEventRecorder("eventexporter-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EventExporter")
@@ -34,6 +34,7 @@ type Config struct {
 	Metrics        MetricsConfig        `yaml:"metrics"`
 	Webhook        WebhookConfig        `yaml:"webhook"`
 	LeaderElection LeaderElectionConfig `yaml:"leaderElection"`
+	Templates      TemplatesConfig      `yaml:"templates"`
 }
 
 type HealthConfig struct {
@@ -53,6 +54,14 @@ type LeaderElectionConfig struct {
 	ResourceID string `yaml:"resourceName"`
 }
 
+// TemplatesConfig locates the templates overriding the embedded ones of the components
+type TemplatesConfig struct {
+	// Directory contains the templates of the components, such as <Directory>/oapserver/templates/deployment.yaml
+	Directory string `yaml:"directory"`
+	// ConfigMapNamespace is the namespace of the ConfigMaps labeled with the component whose templates they override
+	ConfigMapNamespace string `yaml:"configMapNamespace"`
+}
+
 func ParseFile(path string) (*Config, error) {
 	fd, err := os.Open(path)
 	if err != nil {
@@ -20,18 +20,44 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"text/template"
 
 	"github.com/go-logr/logr"
 	l "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/csaupgrade"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/metrics"
+)
+
+// FieldManager is the field manager of the server-side apply requests of the operator
+const FieldManager = "skywalking-swck-operator"
+
+// legacyFieldManagers are the field managers of the previous versions of the operator, which updated the resources
+var legacyFieldManagers = sets.New[string]("manager", "operator")
+
+// DriftPolicy tells what to do if a resource drifts from its desired state
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect reverts the resource to the desired state
+	DriftPolicyCorrect DriftPolicy = "correct"
+	// DriftPolicyReport only reports the drift by an event
+	DriftPolicyReport DriftPolicy = "report"
 )
 
 // Repo provides tools to access templates
@@ -40,6 +66,46 @@ type Repo interface {
 	GetFilesRecursive(path string) ([]string, error)
 }
 
+// Inventoried is implemented by the CRs which record the resources applied for them, the resources
+// recorded but not rendered anymore are pruned by ApplyAll
+type Inventoried interface {
+	GetInventory() []operatorv1alpha1.ResourceRef
+	SetInventory(inventory []operatorv1alpha1.ResourceRef)
+}
+
+// Overridden is implemented by the CRs which record the sources of the templates overriding the embedded ones
+type Overridden interface {
+	GetTemplateOverrides() []operatorv1alpha1.TemplateOverride
+	SetTemplateOverrides(overrides []operatorv1alpha1.TemplateOverride)
+}
+
+// OverridableRepo is implemented by the repos whose templates might be overridden
+type OverridableRepo interface {
+	Repo
+	Overrides() ([]operatorv1alpha1.TemplateOverride, error)
+}
+
+// SnapshotRepo is implemented by the repos whose templates might change between the reads. The snapshot serves
+// the templates as they are when it's taken.
+type SnapshotRepo interface {
+	OverridableRepo
+	Snapshot(ctx context.Context) (Repo, error)
+}
+
+// Snapshot takes a snapshot of the repo if it's supported, otherwise the repo is returned as it is. A reconciliation
+// reads the templates from the snapshot, so it renders and records the templates of the same version.
+func Snapshot(ctx context.Context, repo Repo) (Repo, error) {
+	if r, ok := repo.(SnapshotRepo); ok {
+		return r.Snapshot(ctx)
+	}
+	return repo, nil
+}
+
+// PodTemplated is implemented by the CRs whose pod template is merged into the workloads applied for them
+type PodTemplated interface {
+	GetPodTemplate() *v1.PodTemplateSpec
+}
+
 // Application contains the resource of one single component which is applied to api server
 type Application struct {
 	client.Client
@@ -53,14 +119,22 @@ type Application struct {
 // ApplyAll manifests dependent a single CR
 func (a *Application) ApplyAll(ctx context.Context, manifestFiles []string, log logr.Logger) error {
 	var changedFf []string
+	var inventory []operatorv1alpha1.ResourceRef
 	for _, f := range manifestFiles {
 		sl := log.WithName(f)
-		changed, err := a.Apply(ctx, f, sl, true)
+		changed, objects, err := a.applyManifest(ctx, f, sl, true)
 		if err != nil {
 			l.Error(err, "failed to apply resource")
 			a.Recorder.Eventf(a.CR, nil, v1.EventTypeWarning, "FailedApply", "Failed", "encountered err: %v", err)
 			return err
 		}
+		for _, obj := range objects {
+			ref, err := a.resourceRef(obj)
+			if err != nil {
+				return err
+			}
+			inventory = append(inventory, ref)
+		}
 		if changed {
 			changedFf = append(changedFf, f)
 		}
@@ -68,25 +142,48 @@ func (a *Application) ApplyAll(ctx context.Context, manifestFiles []string, log
 	if len(changedFf) > 0 {
 		a.Recorder.Eventf(a.CR, nil, v1.EventTypeNormal, "Applied", "Applied", "resources: %v", changedFf)
 	}
-	return nil
+	if err := a.prune(ctx, inventory, log); err != nil {
+		return err
+	}
+	return a.recordStatus(ctx, inventory)
 }
 
 // Apply a template represents a component to api server
 func (a *Application) Apply(ctx context.Context, manifest string, log logr.Logger, needCompose bool) (bool, error) {
+	changed, _, err := a.applyManifest(ctx, manifest, log, needCompose)
+	return changed, err
+}
+
+// applyManifest applies the objects rendered from a template, which might be none
+func (a *Application) applyManifest(ctx context.Context, manifest string, log logr.Logger,
+	needCompose bool) (bool, []*unstructured.Unstructured, error) {
 	manifests, err := a.FileRepo.ReadFile(manifest)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
-	proto := &unstructured.Unstructured{}
-	yaml, err := LoadTemplate(string(manifests), a.CR, a.TmplFunc, proto)
+	yaml, objects, err := LoadTemplate(string(manifests), a.CR, a.TmplFunc)
 	if err == ErrNothingLoaded {
 		log.Info("nothing is loaded")
-		return false, nil
+		return false, nil, nil
 	}
 	if err != nil {
-		return false, fmt.Errorf("failed to load %s template: %w yaml: %v", manifest, err, string(yaml))
+		// the rendered yaml is only logged, since the error ends up in the conditions of the CR
+		log.V(1).Info("failed to load template", "yaml", string(yaml))
+		return false, nil, fmt.Errorf("failed to load %s template: %w", manifest, err)
+	}
+	changed := false
+	for _, obj := range objects {
+		ol := log
+		if len(objects) > 1 {
+			ol = log.WithValues("kind", obj.GetKind(), "name", obj.GetName())
+		}
+		c, err := a.apply(ctx, obj, ol, needCompose)
+		if err != nil {
+			return false, nil, err
+		}
+		changed = changed || c
 	}
-	return a.apply(ctx, proto, log, needCompose)
+	return changed, objects, nil
 }
 
 // ApplyFromObject apply an object to api server
@@ -99,51 +196,290 @@ func (a *Application) ApplyFromObject(ctx context.Context, obj runtime.Object, l
 }
 
 func (a *Application) apply(ctx context.Context, obj *unstructured.Unstructured, log logr.Logger, needCompose bool) (bool, error) {
+	outcome, err := a.applyObject(ctx, obj, log, needCompose)
+	if a.recordsMetrics() {
+		metrics.RecordApply(a.GVK.Kind, a.CR.GetNamespace(), a.CR.GetName(), outcome)
+	}
+	return outcome == metrics.ApplyCreated || outcome == metrics.ApplyUpdated, err
+}
+
+// applyObject applies the object, and returns the outcome of it
+func (a *Application) applyObject(ctx context.Context, obj *unstructured.Unstructured, log logr.Logger, needCompose bool) (string, error) {
 	key := client.ObjectKeyFromObject(obj)
 	current := &unstructured.Unstructured{}
 	current.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
 	err := a.Client.Get(ctx, key, current)
+	found := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		return metrics.ApplyFailed, fmt.Errorf("failed to get %v : %w", key, err)
+	}
 
-	if apierrors.IsNotFound(err) {
-		log.Info("could not find existing resource, creating one...")
-		if needCompose {
-			curr, errComp := a.compose(obj)
-			if errComp != nil {
-				return false, fmt.Errorf("failed to compose: %w", errComp)
-			}
-			obj = curr
+	if needCompose {
+		object, err := a.compose(obj)
+		if err != nil {
+			return metrics.ApplyFailed, fmt.Errorf("failed to compose: %w", err)
 		}
+		obj = object
+	}
 
-		if err = a.Client.Create(ctx, obj); err != nil {
-			return false, fmt.Errorf("failed to create: %w", err)
+	if found {
+		if getVersion(current, a.versionKey()) == getVersion(obj, a.versionKey()) {
+			if !needCompose {
+				log.Info("resource keeps the same as before")
+				return metrics.ApplyUnchanged, nil
+			}
+			corrected, err := a.correctDrift(ctx, current, obj, log)
+			if err != nil {
+				return metrics.ApplyFailed, err
+			}
+			if corrected {
+				return metrics.ApplyUpdated, nil
+			}
+			return metrics.ApplyUnchanged, nil
+		}
+		if err := a.upgradeManagedFields(ctx, current); err != nil {
+			return metrics.ApplyFailed, fmt.Errorf("failed to upgrade managed fields: %w", err)
 		}
+	} else {
+		log.Info("could not find existing resource, creating one...")
+	}
 
-		log.Info("created")
-		return true, nil
+	if !found {
+		current = nil
 	}
+	if err := a.serverSideApply(ctx, current, obj); err != nil {
+		return metrics.ApplyFailed, err
+	}
+	if found {
+		log.Info("updated")
+		return metrics.ApplyUpdated, nil
+	}
+	log.Info("created")
+	return metrics.ApplyCreated, nil
+}
+
+// serverSideApply applies the fields of the object as FieldManager. The conflicts with the other managers
+// are reported by an event, then the object is applied again with the ownership of the conflicting fields forced,
+// since the fields rendered from the templates are managed by the operator. The replicas of the current object
+// scaled through the scale subresource are left to the scaler. The current object is nil if it doesn't exist.
+func (a *Application) serverSideApply(ctx context.Context, current, obj *unstructured.Unstructured) error {
+	ac := client.ApplyConfigurationFromUnstructured(withoutScaledReplicas(current, obj))
+	err := a.Client.Apply(ctx, ac, client.FieldOwner(FieldManager))
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsConflict(err) {
+		return fmt.Errorf("failed to apply: %w", err)
+	}
+	a.eventf(v1.EventTypeWarning, "ApplyConflict", "Apply",
+		"fields of %s %s are managed by others, take them over: %v", obj.GetKind(), obj.GetName(), err)
+	if err := a.Client.Apply(ctx, ac, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return fmt.Errorf("failed to apply with conflicts: %w", err)
+	}
+	return nil
+}
+
+// correctDrift compares the live object with the result of applying the desired one, and applies it again if
+// they differ. The drift is only reported if the drift policy of the CR is DriftPolicyReport.
+func (a *Application) correctDrift(ctx context.Context, current, desired *unstructured.Unstructured, log logr.Logger) (bool, error) {
+	drifted, err := a.drifted(ctx, current, desired)
 	if err != nil {
-		return false, fmt.Errorf("failed to get %v : %w", key, err)
+		return false, fmt.Errorf("failed to detect drift: %w", err)
+	}
+	if !drifted {
+		log.Info("resource keeps the same as before")
+		return false, nil
+	}
+	if a.driftPolicy() == DriftPolicyReport {
+		log.Info("drift is detected")
+		a.eventf(v1.EventTypeWarning, "DriftDetected", "DetectDrift",
+			"%s %s drifts from the desired state", desired.GetKind(), desired.GetName())
+		return false, nil
+	}
+	if err := a.Client.Apply(ctx, client.ApplyConfigurationFromUnstructured(withoutScaledReplicas(current, desired)),
+		client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return false, fmt.Errorf("failed to correct drift: %w", err)
 	}
+	log.Info("drift is corrected")
+	if a.recordsMetrics() {
+		metrics.RecordDriftCorrection(a.GVK.Kind, a.CR.GetNamespace(), a.CR.GetName())
+	}
+	a.eventf(v1.EventTypeNormal, "DriftCorrected", "CorrectDrift",
+		"%s %s is reverted to the desired state", desired.GetKind(), desired.GetName())
+	return true, nil
+}
 
-	if needCompose {
-		object, err := a.compose(obj)
+// drifted tells whether the live object differs from the result of a dry-run apply of the desired object.
+// The replicas scaled through the scale subresource, such as by HPAs, are not counted as drift.
+func (a *Application) drifted(ctx context.Context, current, desired *unstructured.Unstructured) (bool, error) {
+	applied := desired.DeepCopy()
+	if scaledBySubresource(current) {
+		if replicas, found, err := unstructured.NestedFieldCopy(current.Object, "spec", "replicas"); err == nil && found {
+			if err := unstructured.SetNestedField(applied.Object, replicas, "spec", "replicas"); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := a.Client.Apply(ctx, client.ApplyConfigurationFromUnstructured(applied), client.FieldOwner(FieldManager),
+		client.ForceOwnership, client.DryRunAll); err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(stripServerFields(current), stripServerFields(applied)), nil
+}
+
+// prune deletes the resources recorded in the inventory of the CR but not rendered anymore. Cluster-scoped resources, such as the RBAC ones, can't be garbage collected through
+// the owner references, so they have to be deleted here as well.
+func (a *Application) prune(ctx context.Context, inventory []operatorv1alpha1.ResourceRef, log logr.Logger) error {
+	cr, ok := a.CR.(Inventoried)
+	if !ok {
+		return nil
+	}
+	rendered := sets.New[string]()
+	for _, ref := range inventory {
+		rendered.Insert(refKey(ref))
+	}
+	var pruned []string
+	for _, ref := range cr.GetInventory() {
+		if rendered.Has(refKey(ref)) {
+			continue
+		}
+		deleted, err := a.delete(ctx, ref, a.controlled)
 		if err != nil {
-			return false, fmt.Errorf("failed to compose: %w", err)
+			return fmt.Errorf("failed to prune %s %s: %w", ref.Kind, ref.Name, err)
 		}
-		obj = object
+		if deleted {
+			log.Info("pruned", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+			pruned = append(pruned, ref.Kind+"/"+ref.Name)
+		}
+	}
+	if len(pruned) > 0 {
+		a.eventf(v1.EventTypeNormal, "Pruned", "Prune", "resources: %v", pruned)
 	}
+	return nil
+}
 
-	if getVersion(current, a.versionKey()) == getVersion(obj, a.versionKey()) {
-		log.Info("resource keeps the same as before")
+// recordStatus records the inventory and the sources of the overriding templates in the status of the CR
+func (a *Application) recordStatus(ctx context.Context, inventory []operatorv1alpha1.ResourceRef) error {
+	// patch a copy, since the spec of the CR might be modified in memory before applying
+	obj := a.CR.DeepCopyObject().(client.Object)
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	changed := false
+	if cr, ok := obj.(Inventoried); ok && !equality.Semantic.DeepEqual(cr.GetInventory(), inventory) {
+		cr.SetInventory(inventory)
+		changed = true
+	}
+	if cr, ok := obj.(Overridden); ok {
+		var overrides []operatorv1alpha1.TemplateOverride
+		if repo, ok := a.FileRepo.(OverridableRepo); ok {
+			var err error
+			if overrides, err = repo.Overrides(); err != nil {
+				return fmt.Errorf("failed to get template overrides: %w", err)
+			}
+		}
+		if !equality.Semantic.DeepEqual(cr.GetTemplateOverrides(), overrides) {
+			cr.SetTemplateOverrides(overrides)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := a.Client.Status().Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("failed to record status: %w", err)
+	}
+	if cr, ok := a.CR.(Inventoried); ok {
+		cr.SetInventory(inventory)
+	}
+	if cr, ok := a.CR.(Overridden); ok {
+		cr.SetTemplateOverrides(obj.(Overridden).GetTemplateOverrides())
+	}
+	return nil
+}
+
+// delete deletes the resource referred if it's owned, which is told by the owned func
+func (a *Application) delete(ctx context.Context, ref operatorv1alpha1.ResourceRef, owned func(metav1.Object) bool) (bool, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	err := a.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, obj)
+	if apierrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !owned(obj) {
 		return false, nil
 	}
-	if err := a.Client.Update(ctx, obj); err != nil {
-		return false, fmt.Errorf("failed to update: %w", err)
+	if err := a.Client.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return false, client.IgnoreNotFound(err)
 	}
-	log.Info("updated")
 	return true, nil
 }
 
+// controlled tells whether the object is controlled by the CR
+func (a *Application) controlled(obj metav1.Object) bool {
+	return metav1.IsControlledBy(obj, a.CR)
+}
+
+// recordsMetrics tells whether the metrics are recorded for the CR. They're only recorded for the CRs of the operator,
+// which are forgotten once deleted, rather than the pods injected with the java agent, which would grow the labels unbounded.
+func (a *Application) recordsMetrics() bool {
+	return a.GVK.Group == operatorv1alpha1.GroupVersion.Group
+}
+
+// resourceRef refers to an applied object, the namespace is dropped if the object is cluster-scoped
+func (a *Application) resourceRef(obj *unstructured.Unstructured) (operatorv1alpha1.ResourceRef, error) {
+	ref := operatorv1alpha1.ResourceRef{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+	}
+	namespaced, err := a.Client.IsObjectNamespaced(obj)
+	if err != nil {
+		return ref, fmt.Errorf("failed to get the scope of %s %s: %w", ref.Kind, ref.Name, err)
+	}
+	if namespaced {
+		ref.Namespace = obj.GetNamespace()
+	}
+	return ref, nil
+}
+
+// refKey identifies the resource referred regardless of the version of its api
+func refKey(ref operatorv1alpha1.ResourceRef) string {
+	gv, _ := schema.ParseGroupVersion(ref.APIVersion)
+	return strings.Join([]string{gv.Group, ref.Kind, ref.Namespace, ref.Name}, "/")
+}
+
+// eventf records an event of the CR if the recorder is set
+func (a *Application) eventf(eventType, reason, action, note string, args ...interface{}) {
+	if a.Recorder != nil {
+		a.Recorder.Eventf(a.CR, nil, eventType, reason, action, note, args...)
+	}
+}
+
+// driftPolicy returns the drift policy in the annotation of the CR
+func (a *Application) driftPolicy() DriftPolicy {
+	if DriftPolicy(a.CR.GetAnnotations()[a.driftPolicyKey()]) == DriftPolicyReport {
+		return DriftPolicyReport
+	}
+	return DriftPolicyCorrect
+}
+
+func (a *Application) driftPolicyKey() string {
+	return a.GVK.Group + "/drift-policy"
+}
+
+// upgradeManagedFields transfers the fields updated by the previous versions of the operator to FieldManager,
+// otherwise they would never be removed by server-side apply once they are dropped from the templates
+func (a *Application) upgradeManagedFields(ctx context.Context, current *unstructured.Unstructured) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(current, legacyFieldManagers, FieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	return a.Client.Patch(ctx, current, client.RawPatch(types.JSONPatchType, patch))
+}
+
 func (a *Application) setVersionAnnotation(o *unstructured.Unstructured) error {
 	h, err := hash(o)
 	if err != nil {
@@ -159,6 +495,11 @@ func (a *Application) versionKey() string {
 
 func (a *Application) compose(object *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	object.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(a.CR, a.GVK)})
+	if cr, ok := a.CR.(PodTemplated); ok {
+		if err := mergePodTemplate(object, cr.GetPodTemplate()); err != nil {
+			return nil, fmt.Errorf("failed to merge pod template: %w", err)
+		}
+	}
 	err := a.setVersionAnnotation(object)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,504 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// scaler is the field manager scaling the workloads through the scale subresource in the tests
+const scaler = "horizontal-pod-autoscaler"
+
+// memRepo serves the templates from memory
+type memRepo map[string]string
+
+func (r memRepo) ReadFile(path string) ([]byte, error) {
+	if content, ok := r[path]; ok {
+		return []byte(content), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (r memRepo) GetFilesRecursive(path string) ([]string, error) {
+	var files []string
+	for f := range r {
+		if strings.HasPrefix(f, path) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+const deploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Name }}-oap
+  namespace: {{ .Namespace }}
+  labels:
+    app: oap
+spec:
+  replicas: 1
+`
+
+func newOAPServer() *operatorv1alpha1.OAPServer {
+	return &operatorv1alpha1.OAPServer{
+		TypeMeta:   metav1.TypeMeta{APIVersion: operatorv1alpha1.GroupVersion.String(), Kind: "OAPServer"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "skywalking", UID: "oap-uid"},
+	}
+}
+
+// dryRunFunc serves a dry-run apply of the object, and fills the object with the response of the server
+type dryRunFunc func(ctx context.Context, c client.WithWatch, obj *unstructured.Unstructured) error
+
+// newTestApplication builds the application of the CR against a fake client. The fake client doesn't set the subresource
+// of the managed fields, so the fields managed by the scaler are marked as updated through the scale subresource.
+// It doesn't honor the dry-run of apply either, so the dry-run applies are skipped, which leave the object as it is.
+func newTestApplication(cr client.Object, repo Repo, objects ...client.Object) (*Application, *events.FakeRecorder) {
+	return newTestApplicationWithDryRun(cr, repo, nil, objects...)
+}
+
+// newTestApplicationWithDryRun builds the application like newTestApplication, whose dry-run applies are served by dryRun
+func newTestApplicationWithDryRun(cr client.Object, repo Repo, dryRun dryRunFunc, objects ...client.Object) (*Application, *events.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), apimeta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), apimeta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), apimeta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), apimeta.RESTScopeRoot)
+	mapper.Add(operatorv1alpha1.GroupVersion.WithKind("OAPServer"), apimeta.RESTScopeNamespace)
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithStatusSubresource(&operatorv1alpha1.OAPServer{}).
+		WithObjects(append([]client.Object{cr}, objects...)...).
+		WithReturnManagedFields().
+		WithInterceptorFuncs(interceptor.Funcs{
+			Apply: func(ctx context.Context, c client.WithWatch, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+				o := &client.ApplyOptions{}
+				o.ApplyOptions(opts)
+				if len(o.DryRun) > 0 {
+					if dryRun == nil {
+						return nil
+					}
+					u, ok := obj.(interface {
+						UnstructuredContent() map[string]interface{}
+						SetUnstructuredContent(map[string]interface{})
+					})
+					if !ok {
+						return fmt.Errorf("unexpected apply configuration %T", obj)
+					}
+					applied := &unstructured.Unstructured{Object: u.UnstructuredContent()}
+					if err := dryRun(ctx, c, applied); err != nil {
+						return err
+					}
+					u.SetUnstructuredContent(applied.Object)
+					return nil
+				}
+				return c.Apply(ctx, obj, opts...)
+			},
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := c.Get(ctx, key, obj, opts...); err != nil {
+					return err
+				}
+				fields := obj.GetManagedFields()
+				for i := range fields {
+					if fields[i].Manager == scaler {
+						fields[i].Subresource = "scale"
+					}
+				}
+				obj.SetManagedFields(fields)
+				return nil
+			},
+		}).
+		Build()
+	recorder := events.NewFakeRecorder(100)
+	return &Application{
+		Client:   c,
+		CR:       cr,
+		FileRepo: repo,
+		GVK:      operatorv1alpha1.GroupVersion.WithKind("OAPServer"),
+		Recorder: recorder,
+	}, recorder
+}
+
+// applyAs applies the fields of the object as the manager, taking over the conflicting fields
+func applyAs(t *testing.T, c client.Client, manager string, obj map[string]interface{}) {
+	t.Helper()
+	// the object is copied, since it's updated by the response
+	u := (&unstructured.Unstructured{Object: obj}).DeepCopy()
+	if err := c.Apply(context.Background(), client.ApplyConfigurationFromUnstructured(u), client.FieldOwner(manager),
+		client.ForceOwnership); err != nil {
+		t.Fatalf("failed to apply as %s: %v", manager, err)
+	}
+}
+
+// deploymentFields are the fields of the applied deployment
+func deploymentFields(labels map[string]interface{}, replicas int64) map[string]interface{} {
+	metadata := map[string]interface{}{"name": "default-oap", "namespace": "skywalking"}
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+	obj := map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": metadata}
+	if replicas > 0 {
+		obj["spec"] = map[string]interface{}{"replicas": replicas}
+	}
+	return obj
+}
+
+func getDeployment(t *testing.T, c client.Client) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "skywalking", Name: "default-oap"}, obj); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	return obj
+}
+
+func replicasOf(obj *unstructured.Unstructured) int64 {
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	return replicas
+}
+
+// reasons drains the events recorded, and returns their reasons
+func reasons(recorder *events.FakeRecorder) map[string]bool {
+	got := make(map[string]bool)
+	for {
+		select {
+		case e := <-recorder.Events:
+			if fields := strings.Fields(e); len(fields) > 1 {
+				got[fields[1]] = true
+			}
+		default:
+			return got
+		}
+	}
+}
+
+func TestApplyKeepsScaledReplicas(t *testing.T) {
+	tests := []struct {
+		name         string
+		manager      string
+		wantReplicas int64
+		wantConflict bool
+	}{
+		{name: "replicas scaled through the scale subresource are kept", manager: scaler, wantReplicas: 5},
+		{name: "replicas updated by others are taken over", manager: "kubectl", wantReplicas: 1, wantConflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := memRepo{"oapserver/templates/deployment.yaml": deploymentTemplate}
+			a, recorder := newTestApplication(newOAPServer(), repo)
+			if _, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			applyAs(t, a.Client, tt.manager, deploymentFields(nil, 5))
+
+			// the hash changes with the spec
+			repo["oapserver/templates/deployment.yaml"] = deploymentTemplate + "  minReadySeconds: 10\n"
+			changed, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !changed {
+				t.Errorf("Apply() = false, want true")
+			}
+			live := getDeployment(t, a.Client)
+			if got := replicasOf(live); got != tt.wantReplicas {
+				t.Errorf("replicas = %d, want %d", got, tt.wantReplicas)
+			}
+			if got, _, _ := unstructured.NestedInt64(live.Object, "spec", "minReadySeconds"); got != 10 {
+				t.Errorf("minReadySeconds = %d, want 10", got)
+			}
+			if got := reasons(recorder)["ApplyConflict"]; got != tt.wantConflict {
+				t.Errorf("ApplyConflict event = %v, want %v", got, tt.wantConflict)
+			}
+		})
+	}
+}
+
+func TestCorrectDrift(t *testing.T) {
+	edited := deploymentFields(map[string]interface{}{"app": "edited"}, 0)
+	scaled := deploymentFields(nil, 5)
+	tests := []struct {
+		name        string
+		driftPolicy DriftPolicy
+		// edits are the fields applied by the managers after the operator
+		edits        map[string]map[string]interface{}
+		wantChanged  bool
+		wantLabel    string
+		wantReplicas int64
+		wantEvent    string
+	}{
+		{name: "no drift", wantLabel: "oap", wantReplicas: 1},
+		{
+			name: "drift is corrected", edits: map[string]map[string]interface{}{"kubectl": edited},
+			wantChanged: true, wantLabel: "oap", wantReplicas: 1, wantEvent: "DriftCorrected",
+		},
+		{
+			name: "drift is reported", driftPolicy: DriftPolicyReport, edits: map[string]map[string]interface{}{"kubectl": edited},
+			wantLabel: "edited", wantReplicas: 1, wantEvent: "DriftDetected",
+		},
+		{
+			name: "scaled replicas are not drift", edits: map[string]map[string]interface{}{scaler: scaled},
+			wantLabel: "oap", wantReplicas: 5,
+		},
+		{
+			name: "scaled replicas are kept while correcting drift", edits: map[string]map[string]interface{}{scaler: scaled, "kubectl": edited},
+			wantChanged: true, wantLabel: "oap", wantReplicas: 5, wantEvent: "DriftCorrected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cr := newOAPServer()
+			if tt.driftPolicy != "" {
+				cr.Annotations = map[string]string{operatorv1alpha1.GroupVersion.Group + "/drift-policy": string(tt.driftPolicy)}
+			}
+			a, recorder := newTestApplication(cr, memRepo{"oapserver/templates/deployment.yaml": deploymentTemplate})
+			if _, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			for manager, fields := range tt.edits {
+				applyAs(t, a.Client, manager, fields)
+			}
+
+			changed, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Apply() = %v, want %v", changed, tt.wantChanged)
+			}
+			live := getDeployment(t, a.Client)
+			if got := live.GetLabels()["app"]; got != tt.wantLabel {
+				t.Errorf("label app = %s, want %s", got, tt.wantLabel)
+			}
+			if got := replicasOf(live); got != tt.wantReplicas {
+				t.Errorf("replicas = %d, want %d", got, tt.wantReplicas)
+			}
+			recorded := reasons(recorder)
+			for _, reason := range []string{"DriftCorrected", "DriftDetected"} {
+				if got := recorded[reason]; got != (reason == tt.wantEvent) {
+					t.Errorf("%s event = %v, want %v", reason, got, reason == tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+// mergeInto merges the fields of the src into the dst, the maps are merged recursively and the other values are replaced
+func mergeInto(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if m, ok := v.(map[string]interface{}); ok {
+			if d, ok := dst[k].(map[string]interface{}); ok {
+				mergeInto(d, m)
+				continue
+			}
+		}
+		dst[k] = runtime.DeepCopyJSONValue(v)
+	}
+}
+
+// serverDryRun responds like API server, which applies the object on top of the live one, then fills the defaulted
+// and server fields
+func serverDryRun(ctx context.Context, c client.WithWatch, obj *unstructured.Unstructured) error {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		return err
+	}
+	result := live.DeepCopy()
+	mergeInto(result.Object, obj.Object)
+	result.SetResourceVersion(live.GetResourceVersion() + "1")
+	result.SetGeneration(live.GetGeneration() + 1)
+	result.SetManagedFields(append(live.GetManagedFields(), metav1.ManagedFieldsEntry{
+		Manager: FieldManager, Operation: metav1.ManagedFieldsOperationApply, APIVersion: "apps/v1",
+		Time: &metav1.Time{Time: time.Now()},
+	}))
+	defaultDeployment(result.Object)
+	obj.Object = result.Object
+	return nil
+}
+
+// defaultDeployment sets the fields defaulted by API server, and the status
+func defaultDeployment(obj map[string]interface{}) {
+	mergeInto(obj, map[string]interface{}{
+		"metadata": map[string]interface{}{"uid": "deployment-uid", "creationTimestamp": "2024-01-01T00:00:00Z"},
+		"spec": map[string]interface{}{
+			"revisionHistoryLimit":    int64(10),
+			"progressDeadlineSeconds": int64(600),
+			"strategy": map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": "25%"},
+			},
+		},
+		"status": map[string]interface{}{"replicas": int64(1), "readyReplicas": int64(1), "observedGeneration": int64(1)},
+	})
+}
+
+func TestDriftWithServerFields(t *testing.T) {
+	tests := []struct {
+		name string
+		// edits are the fields applied by kubectl after the operator
+		edits       map[string]interface{}
+		wantChanged bool
+		wantEvent   bool
+	}{
+		{name: "the defaulted and server fields are not drift"},
+		{
+			name: "drift is detected beside the defaulted and server fields", edits: deploymentFields(map[string]interface{}{"app": "edited"}, 0),
+			wantChanged: true, wantEvent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a, recorder := newTestApplicationWithDryRun(newOAPServer(), memRepo{"oapserver/templates/deployment.yaml": deploymentTemplate}, serverDryRun)
+			if _, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			// the live object is defaulted by API server
+			live := getDeployment(t, a.Client)
+			defaultDeployment(live.Object)
+			if err := a.Client.Update(ctx, live); err != nil {
+				t.Fatalf("failed to default the deployment: %v", err)
+			}
+			if tt.edits != nil {
+				applyAs(t, a.Client, "kubectl", tt.edits)
+			}
+
+			changed, err := a.Apply(ctx, "oapserver/templates/deployment.yaml", logr.Discard(), true)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Apply() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := reasons(recorder)["DriftCorrected"]; got != tt.wantEvent {
+				t.Errorf("DriftCorrected event = %v, want %v", got, tt.wantEvent)
+			}
+			if got := getDeployment(t, a.Client).GetLabels()["app"]; got != "oap" {
+				t.Errorf("label app = %s, want oap", got)
+			}
+		})
+	}
+}
+
+// newObject builds an object referred by the ref, which is controlled by the owner if it's not nil
+func newObject(ref operatorv1alpha1.ResourceRef, owner client.Object) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetNamespace(ref.Namespace)
+	obj.SetName(ref.Name)
+	if owner != nil {
+		gvk := operatorv1alpha1.GroupVersion.WithKind(owner.GetObjectKind().GroupVersionKind().Kind)
+		obj.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(owner, gvk)})
+	}
+	return obj
+}
+
+func TestPrune(t *testing.T) {
+	cr := newOAPServer()
+	another := newOAPServer()
+	another.Name, another.UID = "another", "another-uid"
+	configMap := func(name string) operatorv1alpha1.ResourceRef {
+		return operatorv1alpha1.ResourceRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "skywalking", Name: name}
+	}
+	clusterRole := func(version, name string) operatorv1alpha1.ResourceRef {
+		return operatorv1alpha1.ResourceRef{APIVersion: "rbac.authorization.k8s.io/" + version, Kind: "ClusterRole", Name: name}
+	}
+	tests := []struct {
+		name     string
+		ref      operatorv1alpha1.ResourceRef
+		owner    client.Object
+		rendered bool
+		want     bool
+	}{
+		{name: "rendered", ref: configMap("rendered"), owner: cr, rendered: true, want: true},
+		{name: "not rendered", ref: configMap("stale"), owner: cr, want: false},
+		{name: "not rendered but controlled by another CR", ref: configMap("another"), owner: another, want: true},
+		{name: "not rendered but not controlled", ref: configMap("unowned"), want: true},
+		{name: "cluster-scoped", ref: clusterRole("v1", "stale"), owner: cr, want: false},
+		{name: "rendered in another version", ref: clusterRole("v1beta1", "rendered"), owner: cr, rendered: true, want: true},
+	}
+	var inventory, rendered []operatorv1alpha1.ResourceRef
+	var objects []*unstructured.Unstructured
+	for _, tt := range tests {
+		inventory = append(inventory, tt.ref)
+		if tt.rendered {
+			ref := tt.ref
+			ref.APIVersion = strings.Replace(ref.APIVersion, "v1beta1", "v1", 1)
+			rendered = append(rendered, ref)
+		}
+		obj := newObject(tt.ref, tt.owner)
+		obj.SetAPIVersion(strings.Replace(obj.GetAPIVersion(), "v1beta1", "v1", 1))
+		objects = append(objects, obj)
+	}
+	// the missing resources are skipped
+	inventory = append(inventory, configMap("missing"))
+	cr.SetInventory(inventory)
+
+	ctx := context.Background()
+	a, recorder := newTestApplication(cr, memRepo{})
+	for _, obj := range objects {
+		if err := a.Client.Create(ctx, obj); err != nil {
+			t.Fatalf("failed to create %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	if err := a.prune(ctx, rendered, logr.Discard()); err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := objects[i]
+			err := a.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopy())
+			if exists := err == nil; exists != tt.want {
+				t.Errorf("%s %s exists = %v, want %v, err %v", obj.GetKind(), obj.GetName(), exists, tt.want, err)
+			}
+		})
+	}
+	if !reasons(recorder)["Pruned"] {
+		t.Errorf("Pruned event is not recorded")
+	}
+}
@@ -0,0 +1,92 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// maxMessageLength is the max length of the message of a condition
+const maxMessageLength = 32768
+
+// Conditions computes the Ready, Progressing and Degraded conditions from the result of the last apply
+// and the number of ready replicas. The transition time of an unchanged condition is kept from current.
+func Conditions(current []metav1.Condition, generation int64, err error, ready, desired int32) []metav1.Condition {
+	return StateConditions(current, generation, err, ready >= desired, fmt.Sprintf("%d/%d replicas are ready", ready, desired))
+}
+
+// StateConditions computes the Ready, Progressing and Degraded conditions of the CRs without replicas, whose
+// readiness is told by the message. The conditions of the other types, which are left by the former versions
+// of the operator, are removed.
+func StateConditions(current []metav1.Condition, generation int64, err error, ready bool, message string) []metav1.Condition {
+	conditions := make([]metav1.Condition, 0, len(current))
+	for _, c := range current {
+		switch c.Type {
+		case operatorv1alpha1.ConditionTypeReady, operatorv1alpha1.ConditionTypeProgressing, operatorv1alpha1.ConditionTypeDegraded:
+			conditions = append(conditions, c)
+		}
+	}
+	set := func(t string, status metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&conditions, metav1.Condition{
+			Type:               t,
+			Status:             status,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            truncate(message),
+		})
+	}
+	switch {
+	case err != nil:
+		reason := operatorv1alpha1.ReasonApplyFailed
+		if r := apierrors.ReasonForError(err); r != metav1.StatusReasonUnknown {
+			reason = "Apply" + string(r)
+		}
+		set(operatorv1alpha1.ConditionTypeReady, metav1.ConditionFalse, reason, err.Error())
+		set(operatorv1alpha1.ConditionTypeProgressing, metav1.ConditionFalse, reason, err.Error())
+		set(operatorv1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, reason, err.Error())
+	case !ready:
+		set(operatorv1alpha1.ConditionTypeReady, metav1.ConditionFalse, operatorv1alpha1.ReasonInProgress, message)
+		set(operatorv1alpha1.ConditionTypeProgressing, metav1.ConditionTrue, operatorv1alpha1.ReasonInProgress, message)
+		set(operatorv1alpha1.ConditionTypeDegraded, metav1.ConditionFalse, operatorv1alpha1.ReasonInProgress, message)
+	default:
+		set(operatorv1alpha1.ConditionTypeReady, metav1.ConditionTrue, operatorv1alpha1.ReasonAvailable, message)
+		set(operatorv1alpha1.ConditionTypeProgressing, metav1.ConditionFalse, operatorv1alpha1.ReasonAvailable, message)
+		set(operatorv1alpha1.ConditionTypeDegraded, metav1.ConditionFalse, operatorv1alpha1.ReasonAvailable, message)
+	}
+	return conditions
+}
+
+// truncate cuts the message down to maxMessageLength, at the start of a rune
+func truncate(message string) string {
+	if len(message) <= maxMessageLength {
+		return message
+	}
+	const ellipsis = "..."
+	n := maxMessageLength - len(ellipsis)
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	return message[:n] + ellipsis
+}
@@ -0,0 +1,121 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+func TestConditions(t *testing.T) {
+	legacy := []metav1.Condition{
+		{Type: "Available", Status: metav1.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+		{Type: "ReplicaFailure", Status: metav1.ConditionFalse},
+	}
+	tests := []struct {
+		name          string
+		current       []metav1.Condition
+		err           error
+		ready         int32
+		desired       int32
+		wantReady     metav1.ConditionStatus
+		wantReason    string
+		wantMessage   string
+		wantTruncated bool
+	}{
+		{
+			name:        "ready",
+			ready:       2,
+			desired:     2,
+			wantReady:   metav1.ConditionTrue,
+			wantReason:  operatorv1alpha1.ReasonAvailable,
+			wantMessage: "2/2 replicas are ready",
+		},
+		{
+			name:        "in progress",
+			ready:       1,
+			desired:     2,
+			wantReady:   metav1.ConditionFalse,
+			wantReason:  operatorv1alpha1.ReasonInProgress,
+			wantMessage: "1/2 replicas are ready",
+		},
+		{
+			name:        "apply failed",
+			err:         apierrors.NewForbidden(schema.GroupResource{Resource: "clusterroles"}, "oap", errors.New("denied")),
+			wantReady:   metav1.ConditionFalse,
+			wantReason:  "ApplyForbidden",
+			wantMessage: `clusterroles "oap" is forbidden: denied`,
+		},
+		{
+			name:          "long error is truncated",
+			err:           errors.New(strings.Repeat("界", maxMessageLength)),
+			wantReady:     metav1.ConditionFalse,
+			wantReason:    operatorv1alpha1.ReasonApplyFailed,
+			wantTruncated: true,
+		},
+		{
+			name:        "legacy conditions are removed",
+			current:     legacy,
+			ready:       1,
+			desired:     1,
+			wantReady:   metav1.ConditionTrue,
+			wantReason:  operatorv1alpha1.ReasonAvailable,
+			wantMessage: "1/1 replicas are ready",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Conditions(tt.current, 3, tt.err, tt.ready, tt.desired)
+			if len(got) != 3 {
+				t.Fatalf("Conditions() = %v, want Ready, Progressing and Degraded", got)
+			}
+			for _, c := range got {
+				if c.ObservedGeneration != 3 || c.Reason != tt.wantReason {
+					t.Errorf("%s condition = %v, want the generation 3 and the reason %s", c.Type, c, tt.wantReason)
+				}
+				if tt.wantTruncated {
+					if len(c.Message) > maxMessageLength || !utf8.ValidString(c.Message) {
+						t.Errorf("%s message of %d bytes is not truncated to a valid string", c.Type, len(c.Message))
+					}
+				} else if c.Message != tt.wantMessage {
+					t.Errorf("%s message = %s, want %s", c.Type, c.Message, tt.wantMessage)
+				}
+			}
+			if c := apimeta.FindStatusCondition(got, operatorv1alpha1.ConditionTypeReady); c.Status != tt.wantReady {
+				t.Errorf("Ready = %s, want %s", c.Status, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestStateConditions(t *testing.T) {
+	got := StateConditions(nil, 1, nil, false, "the dynamic configuration is stopped")
+	c := apimeta.FindStatusCondition(got, operatorv1alpha1.ConditionTypeProgressing)
+	if c == nil || c.Status != metav1.ConditionTrue || c.Message != "the dynamic configuration is stopped" {
+		t.Errorf("Progressing condition = %v, want True with the message of the state", c)
+	}
+}
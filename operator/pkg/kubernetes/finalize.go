@@ -0,0 +1,202 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// Finalizer holds the deletion of a CR until its cluster-scoped and unowned resources, which can't be garbage
+// collected through the owner references, are deleted
+const Finalizer = "operator.skywalking.apache.org/finalizer"
+
+// Conditioned is implemented by the CRs which report the progress of their finalization by the conditions
+type Conditioned interface {
+	GetConditions() []metav1.Condition
+	SetConditions(conditions []metav1.Condition)
+}
+
+// AddFinalizer adds the Finalizer to the CR unless it's there. It should be called before the CR is modified in memory,
+// since the CR is refreshed by the response of the patch.
+func (a *Application) AddFinalizer(ctx context.Context) error {
+	if controllerutil.ContainsFinalizer(a.CR, Finalizer) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(a.CR.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	controllerutil.AddFinalizer(a.CR, Finalizer)
+	if err := a.Client.Patch(ctx, a.CR, patch); err != nil {
+		return fmt.Errorf("failed to add finalizer: %w", err)
+	}
+	return nil
+}
+
+// Finalize deletes the cluster-scoped resources recorded in the inventory of the CR being deleted, and the unowned ones
+// passed in. The cluster-scoped resources still recorded by another CR of the same kind are kept for it. The resources
+// being deleted are reported by the conditions of the CR, and the Finalizer is removed once all of them are gone.
+// It returns whether the finalization is done.
+func (a *Application) Finalize(ctx context.Context, unowned []operatorv1alpha1.ResourceRef, log logr.Logger) (bool, error) {
+	if !controllerutil.ContainsFinalizer(a.CR, Finalizer) {
+		return true, nil
+	}
+	shared, err := a.sharedRefs(ctx)
+	if err != nil {
+		return false, err
+	}
+	var deleting []operatorv1alpha1.ResourceRef
+	if cr, ok := a.CR.(Inventoried); ok {
+		for _, ref := range cr.GetInventory() {
+			if ref.Namespace != "" || shared.Has(refKey(ref)) {
+				continue
+			}
+			deleted, err := a.delete(ctx, ref, a.controlledByKind)
+			if err != nil {
+				return false, fmt.Errorf("failed to delete %s %s: %w", ref.Kind, ref.Name, err)
+			}
+			if deleted {
+				deleting = append(deleting, ref)
+			}
+		}
+	}
+	for _, ref := range unowned {
+		deleted, err := a.delete(ctx, ref, func(metav1.Object) bool { return true })
+		if err != nil {
+			return false, fmt.Errorf("failed to delete %s %s: %w", ref.Kind, ref.Name, err)
+		}
+		if deleted {
+			deleting = append(deleting, ref)
+		}
+	}
+	var deleted, remaining []string
+	for _, ref := range deleting {
+		log.Info("deleted", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+		deleted = append(deleted, ref.Kind+"/"+ref.Name)
+		exists, err := a.exists(ctx, ref)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			remaining = append(remaining, ref.Kind+"/"+ref.Name)
+		}
+	}
+	if len(deleted) > 0 {
+		a.eventf(v1.EventTypeNormal, "Finalized", "Finalize", "resources: %v", deleted)
+	}
+	if len(remaining) > 0 {
+		return false, a.reportFinalizing(ctx, fmt.Sprintf("waiting for the deletion of %v", remaining))
+	}
+	patch := client.MergeFromWithOptions(a.CR.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(a.CR, Finalizer)
+	if err := a.Client.Patch(ctx, a.CR, patch); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return true, nil
+}
+
+// sharedRefs returns the keys of the cluster-scoped resources recorded by the other CRs of the same kind
+// which are not being deleted
+func (a *Application) sharedRefs(ctx context.Context) (sets.Set[string], error) {
+	shared := sets.New[string]()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(a.GVK.GroupVersion().WithKind(a.GVK.Kind + "List"))
+	if err := a.Client.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", a.GVK.Kind, err)
+	}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.GetUID() == a.CR.GetUID() || item.GetDeletionTimestamp() != nil {
+			continue
+		}
+		inventory, _, _ := unstructured.NestedSlice(item.Object, "status", "inventory")
+		for _, r := range inventory {
+			m, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ref := operatorv1alpha1.ResourceRef{}
+			ref.APIVersion, _ = m["apiVersion"].(string)
+			ref.Kind, _ = m["kind"].(string)
+			ref.Namespace, _ = m["namespace"].(string)
+			ref.Name, _ = m["name"].(string)
+			shared.Insert(refKey(ref))
+		}
+	}
+	return shared, nil
+}
+
+// controlledByKind tells whether the object is controlled by a CR of the same kind. A cluster-scoped resource kept for
+// another CR is still controlled by the deleted one until the other CR applies it again, so it's not required
+// to be controlled by this CR.
+func (a *Application) controlledByKind(obj metav1.Object) bool {
+	owner := metav1.GetControllerOfNoCopy(obj)
+	if owner == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	return err == nil && gv.Group == a.GVK.Group && owner.Kind == a.GVK.Kind
+}
+
+// exists tells whether the resource referred still exists
+func (a *Application) exists(ctx context.Context, ref operatorv1alpha1.ResourceRef) (bool, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	err := a.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, obj)
+	if apierrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// reportFinalizing reports the finalization in progress by the conditions of the CR
+func (a *Application) reportFinalizing(ctx context.Context, message string) error {
+	obj := a.CR.DeepCopyObject().(client.Object)
+	cr, ok := obj.(Conditioned)
+	if !ok {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	conditions := cr.GetConditions()
+	for _, c := range []metav1.Condition{
+		{Type: operatorv1alpha1.ConditionTypeReady, Status: metav1.ConditionFalse},
+		{Type: operatorv1alpha1.ConditionTypeProgressing, Status: metav1.ConditionTrue},
+	} {
+		c.ObservedGeneration = obj.GetGeneration()
+		c.Reason = operatorv1alpha1.ReasonFinalizing
+		c.Message = message
+		apimeta.SetStatusCondition(&conditions, c)
+	}
+	cr.SetConditions(conditions)
+	if err := a.Client.Status().Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("failed to report finalizing: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+)
+
+// holder is the finalizer holding the deletion of a resource in the tests
+const holder = "test/holder"
+
+func TestFinalize(t *testing.T) {
+	clusterRole := func(name string) operatorv1alpha1.ResourceRef {
+		return operatorv1alpha1.ResourceRef{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: name}
+	}
+	shared := clusterRole("shared")
+	exclusive := clusterRole("exclusive")
+	uncontrolled := clusterRole("uncontrolled")
+	namespaced := operatorv1alpha1.ResourceRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "skywalking", Name: "config"}
+	unowned := operatorv1alpha1.ResourceRef{APIVersion: "v1", Kind: "Secret", Namespace: "istio-system", Name: "cacerts"}
+
+	now := metav1.Now()
+	cr := newOAPServer()
+	cr.DeletionTimestamp = &now
+	cr.Finalizers = []string{Finalizer}
+	cr.SetInventory([]operatorv1alpha1.ResourceRef{shared, exclusive, uncontrolled, namespaced})
+	another := newOAPServer()
+	another.Name, another.UID = "another", "another-uid"
+	another.SetInventory([]operatorv1alpha1.ResourceRef{shared})
+
+	ctx := context.Background()
+	a, recorder := newTestApplication(cr.DeepCopy(), memRepo{}, another)
+	for _, ref := range []operatorv1alpha1.ResourceRef{shared, exclusive, uncontrolled, namespaced, unowned} {
+		var owner client.Object
+		if ref != uncontrolled && ref != unowned {
+			owner = cr
+		}
+		obj := newObject(ref, owner)
+		if ref == exclusive {
+			// the deletion is held until the finalizer is removed
+			obj.SetFinalizers([]string{holder})
+		}
+		if err := a.Client.Create(ctx, obj); err != nil {
+			t.Fatalf("failed to create %s %s: %v", ref.Kind, ref.Name, err)
+		}
+	}
+	exists := func(ref operatorv1alpha1.ResourceRef) bool {
+		t.Helper()
+		exists, err := a.exists(ctx, ref)
+		if err != nil {
+			t.Fatalf("failed to get %s %s: %v", ref.Kind, ref.Name, err)
+		}
+		return exists
+	}
+	getCR := func() *operatorv1alpha1.OAPServer {
+		t.Helper()
+		got := &operatorv1alpha1.OAPServer{}
+		if err := a.Client.Get(ctx, client.ObjectKeyFromObject(cr), got); err != nil {
+			t.Fatalf("failed to get the CR: %v", err)
+		}
+		return got
+	}
+
+	done, err := a.Finalize(ctx, []operatorv1alpha1.ResourceRef{unowned}, logr.Discard())
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	if done {
+		t.Errorf("Finalize() = true while %s %s is being deleted", exclusive.Kind, exclusive.Name)
+	}
+	for _, tt := range []struct {
+		name string
+		ref  operatorv1alpha1.ResourceRef
+		want bool
+	}{
+		{name: "shared with another CR", ref: shared, want: true},
+		{name: "held by a finalizer", ref: exclusive, want: true},
+		{name: "not controlled by the kind", ref: uncontrolled, want: true},
+		{name: "namespaced", ref: namespaced, want: true},
+		{name: "unowned", ref: unowned, want: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exists(tt.ref); got != tt.want {
+				t.Errorf("%s %s exists = %v, want %v", tt.ref.Kind, tt.ref.Name, got, tt.want)
+			}
+		})
+	}
+	got := getCR()
+	if !controllerutil.ContainsFinalizer(got, Finalizer) {
+		t.Fatalf("finalizer is removed before the deletion completes")
+	}
+	if c := apimeta.FindStatusCondition(got.Status.Conditions, operatorv1alpha1.ConditionTypeProgressing); c == nil ||
+		c.Status != metav1.ConditionTrue || c.Reason != operatorv1alpha1.ReasonFinalizing {
+		t.Errorf("Progressing condition = %v, want True with reason %s", c, operatorv1alpha1.ReasonFinalizing)
+	}
+	if !reasons(recorder)["Finalized"] {
+		t.Errorf("Finalized event is not recorded")
+	}
+
+	// release the held resource, then the finalization completes
+	held := newObject(exclusive, nil)
+	if err := a.Client.Get(ctx, client.ObjectKeyFromObject(held), held); err != nil {
+		t.Fatalf("failed to get %s %s: %v", exclusive.Kind, exclusive.Name, err)
+	}
+	held.SetFinalizers(nil)
+	if err := a.Client.Update(ctx, held); err != nil {
+		t.Fatalf("failed to release %s %s: %v", exclusive.Kind, exclusive.Name, err)
+	}
+	if exists(exclusive) {
+		t.Fatalf("%s %s still exists after its finalizer is removed", exclusive.Kind, exclusive.Name)
+	}
+	a.CR = getCR()
+	done, err = a.Finalize(ctx, []operatorv1alpha1.ResourceRef{unowned}, logr.Discard())
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	if !done {
+		t.Errorf("Finalize() = false after the resources are deleted")
+	}
+	if !exists(shared) {
+		t.Errorf("%s %s shared with another CR is deleted", shared.Kind, shared.Name)
+	}
+	if err := a.Client.Get(ctx, client.ObjectKeyFromObject(cr), &operatorv1alpha1.OAPServer{}); err == nil {
+		t.Errorf("CR still exists after its finalizer is removed")
+	}
+}
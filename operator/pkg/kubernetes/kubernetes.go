@@ -22,15 +22,16 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"text/template"
-	"unicode"
 
 	"github.com/Masterminds/sprig/v3"
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/ghodss/yaml"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 )
 
 var ErrNothingLoaded = errors.New("LoadTemplate: failed load anything from manifests")
@@ -52,13 +53,39 @@ func ApplyOverlay(current, overlay runtime.Object) error {
 	return runtime.DecodeInto(unstructured.UnstructuredJSONScheme, merged, current)
 }
 
-// LoadTemplate loads a YAML file to a component
-func LoadTemplate(manifest string, values interface{}, funcMap template.FuncMap, spec interface{}) ([]byte, error) {
+// LoadTemplate loads the objects of a YAML file, which might contain multiple documents or lists
+func LoadTemplate(manifest string, values interface{}, funcMap template.FuncMap) ([]byte, []*unstructured.Unstructured, error) {
 	bb, err := GenerateManifests(manifest, values, funcMap)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	docs, err := splitDocuments(string(bb))
+	if err != nil {
+		return bb, nil, err
+	}
+	objects := make([]*unstructured.Unstructured, 0, len(docs))
+	for _, doc := range docs {
+		// decode into Unstructured, which keeps the integers as int64 rather than float64
+		object := &unstructured.Unstructured{}
+		if err := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(doc), len(doc)).Decode(object); err != nil {
+			return bb, nil, err
+		}
+		if !object.IsList() {
+			objects = append(objects, object)
+			continue
+		}
+		list, err := object.ToList()
+		if err != nil {
+			return bb, nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
 	}
-	return bb, yaml.Unmarshal(bb, spec)
+	if len(objects) < 1 {
+		return bb, nil, ErrNothingLoaded
+	}
+	return bb, objects, nil
 }
 
 // GenerateManifests generate manifests from templates, CR and values
@@ -96,25 +123,41 @@ func stripCharacters(bb []byte) []byte {
 	s := string(bb)
 	s = strings.TrimSpace(s)
 	s = stripComment(s)
-	s = strings.TrimSpace(s)
 	return []byte(s)
 }
 
-const commentChars = "#"
+const documentSeparator = "---\n"
 
+// stripComment drops the documents which only contain comments. The comments inside the documents are left
+// to the YAML parser, since '#' might be a part of a value, such as a password, a URL or a regex.
 func stripComment(source string) string {
-	sc := bufio.NewScanner(strings.NewReader(source))
-	ll := make([]string, 0)
-	for sc.Scan() {
-		l := sc.Text()
-		if cut := strings.IndexAny(l, commentChars); cut >= 0 {
-			l = strings.TrimRightFunc(l[:cut], unicode.IsSpace)
+	docs, err := splitDocuments(source)
+	if err != nil {
+		// leave the broken documents to the parser, which reports the error
+		return source
+	}
+	return strings.Join(docs, documentSeparator)
+}
+
+// splitDocuments splits the YAML documents, and drops the empty ones
+func splitDocuments(source string) ([]string, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(source)))
+	docs := make([]string, 0)
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
 		}
-		if strings.TrimSpace(l) != "" {
-			ll = append(ll, l)
+		var v interface{}
+		if err := yaml.Unmarshal(doc, &v); err == nil && v == nil {
+			continue
 		}
+		// keep the line break of the last line, which is a part of the block scalars ending there
+		docs = append(docs, strings.Trim(string(doc), "\n")+"\n")
 	}
-	return strings.Join(ll, "\n")
 }
 
 type ErrorCollector []error
@@ -23,9 +23,17 @@ import (
 	"hash/fnv"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 )
 
+// workloadKinds are the kinds of the workloads whose pod template is at spec.template
+var workloadKinds = sets.New[string]("Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job")
+
 func getVersion(o *unstructured.Unstructured, key string) string {
 	ann := o.GetAnnotations()
 	if ann == nil {
@@ -93,3 +101,84 @@ func alphanumeric(in []byte) string {
 	out := hash.Sum(make([]byte, 0, 8))
 	return hex.EncodeToString(out)
 }
+
+// stripServerFields strips the fields maintained by the api server, which are not counted as drift
+func stripServerFields(o *unstructured.Unstructured) map[string]interface{} {
+	c := o.DeepCopy()
+	unstructured.RemoveNestedField(c.Object, "status")
+	unstructured.RemoveNestedField(c.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(c.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(c.Object, "metadata", "generation")
+	return c.Object
+}
+
+// scaledBySubresource tells whether the object has been scaled through the scale subresource
+func scaledBySubresource(o *unstructured.Unstructured) bool {
+	for _, f := range o.GetManagedFields() {
+		if f.Subresource == "scale" {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutScaledReplicas returns a copy of the desired object, whose replicas are dropped if the current one has been
+// scaled through the scale subresource, such as by an HPA, so that applying it doesn't take the replicas back
+func withoutScaledReplicas(current, desired *unstructured.Unstructured) *unstructured.Unstructured {
+	c := desired.DeepCopy()
+	if current != nil && scaledBySubresource(current) {
+		unstructured.RemoveNestedField(c.Object, "spec", "replicas")
+	}
+	return c
+}
+
+// mergePodTemplate merges the overlay into the pod template of a workload by strategic merge patch
+func mergePodTemplate(o *unstructured.Unstructured, overlay *corev1.PodTemplateSpec) error {
+	if overlay == nil || !workloadKinds.Has(o.GetKind()) {
+		return nil
+	}
+	template, found, err := unstructured.NestedMap(o.Object, "spec", "template")
+	if err != nil || !found {
+		return err
+	}
+	original, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	p, err := runtime.DefaultUnstructuredConverter.ToUnstructured(overlay)
+	if err != nil {
+		return err
+	}
+	// the zero values of the fields without omitempty would remove the rendered ones
+	patch, err := json.Marshal(dropNulls(p))
+	if err != nil {
+		return err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+	template = make(map[string]interface{})
+	if err := utiljson.Unmarshal(merged, &template); err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(o.Object, template, "spec", "template")
+}
+
+func dropNulls(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if e == nil {
+				delete(t, k)
+				continue
+			}
+			t[k] = dropNulls(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = dropNulls(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package metrics exposes the metrics of the operator through the metrics endpoint of controller-runtime
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The outcomes of applying a resource
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+	ApplyFailed    = "failed"
+)
+
+var (
+	applyTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swck_operator_apply_total",
+		Help: "Total number of the resources applied for the CRs by outcome, which is created, updated, unchanged or failed",
+	}, []string{"kind", "namespace", "name", "outcome"})
+	driftCorrectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swck_operator_drift_corrections_total",
+		Help: "Total number of the resources of the CRs reverted to their desired state",
+	}, []string{"kind", "namespace", "name"})
+	lastSuccessfulReconcile = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swck_operator_last_successful_reconcile_timestamp_seconds",
+		Help: "Unix time of the last reconciliation of the CRs applying all of their resources",
+	}, []string{"kind", "namespace", "name"})
+	componentReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swck_operator_component_ready",
+		Help: "Whether the Ready condition of the CRs is true, which is 1, or not, which is 0",
+	}, []string{"kind", "namespace", "name"})
+	injectionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swck_operator_javaagent_injection_total",
+		Help: "Total number of the pods injected with the java agent by the webhook by outcome, which is succeeded or failed",
+	}, []string{"namespace", "outcome"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(applyTotal, driftCorrectionsTotal, lastSuccessfulReconcile, componentReady, injectionTotal)
+}
+
+// RecordApply counts the outcome of applying a resource for the CR
+func RecordApply(kind, namespace, name, outcome string) {
+	applyTotal.WithLabelValues(kind, namespace, name, outcome).Inc()
+}
+
+// RecordDriftCorrection counts a resource of the CR reverted to its desired state
+func RecordDriftCorrection(kind, namespace, name string) {
+	driftCorrectionsTotal.WithLabelValues(kind, namespace, name).Inc()
+}
+
+// RecordReconciled records the readiness of the CR, and the time of its reconciliation if it applied all the resources
+func RecordReconciled(kind, namespace, name string, ready, applied bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	componentReady.WithLabelValues(kind, namespace, name).Set(value)
+	if applied {
+		lastSuccessfulReconcile.WithLabelValues(kind, namespace, name).SetToCurrentTime()
+	}
+}
+
+// RecordInjection counts the outcome of injecting the java agent into a pod
+func RecordInjection(namespace string, succeeded bool) {
+	outcome := "succeeded"
+	if !succeeded {
+		outcome = "failed"
+	}
+	injectionTotal.WithLabelValues(namespace, outcome).Inc()
+}
+
+// Forget deletes the metrics of the deleted CR
+func Forget(kind, namespace, name string) {
+	labels := prometheus.Labels{"kind": kind, "namespace": namespace, "name": name}
+	applyTotal.DeletePartialMatch(labels)
+	driftCorrectionsTotal.DeletePartialMatch(labels)
+	lastSuccessfulReconcile.DeletePartialMatch(labels)
+	componentReady.DeletePartialMatch(labels)
+}
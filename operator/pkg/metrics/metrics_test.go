@@ -0,0 +1,84 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordApply(t *testing.T) {
+	applyTotal.Reset()
+	RecordApply("OAPServer", "skywalking", "default", ApplyCreated)
+	RecordApply("OAPServer", "skywalking", "default", ApplyUnchanged)
+	RecordApply("OAPServer", "skywalking", "default", ApplyUnchanged)
+	RecordApply("UI", "skywalking", "default", ApplyFailed)
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{name: "created", labels: []string{"OAPServer", "skywalking", "default", ApplyCreated}, want: 1},
+		{name: "unchanged", labels: []string{"OAPServer", "skywalking", "default", ApplyUnchanged}, want: 2},
+		{name: "another kind of the same name", labels: []string{"UI", "skywalking", "default", ApplyFailed}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(applyTotal.WithLabelValues(tt.labels...)); got != tt.want {
+				t.Errorf("swck_operator_apply_total%v = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForget(t *testing.T) {
+	applyTotal.Reset()
+	driftCorrectionsTotal.Reset()
+	lastSuccessfulReconcile.Reset()
+	componentReady.Reset()
+	for _, name := range []string{"default", "another"} {
+		RecordApply("OAPServer", "skywalking", name, ApplyCreated)
+		RecordApply("OAPServer", "skywalking", name, ApplyUpdated)
+		RecordDriftCorrection("OAPServer", "skywalking", name)
+		RecordReconciled("OAPServer", "skywalking", name, true, true)
+	}
+	RecordApply("UI", "skywalking", "default", ApplyCreated)
+
+	Forget("OAPServer", "skywalking", "default")
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		want      int
+	}{
+		{name: "apply of the others are kept", collector: applyTotal, want: 3},
+		{name: "drift corrections", collector: driftCorrectionsTotal, want: 1},
+		{name: "last successful reconcile", collector: lastSuccessfulReconcile, want: 1},
+		{name: "component ready", collector: componentReady, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.CollectAndCount(tt.collector); got != tt.want {
+				t.Errorf("series = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -65,6 +65,16 @@ const (
 // log is for logging in this package.
 var log = logf.Log.WithName("injector")
 
+// instanceNameEnv names the service instance of the agent after the pod, so that the custom metrics adapter
+// could map the pod to its service instance. It's overridden by the agent.instance_name annotation, or
+// an env of the same name in the container or the SwAgent.
+var instanceNameEnv = corev1.EnvVar{
+	Name: "SW_AGENT_INSTANCE_NAME",
+	ValueFrom: &corev1.EnvVarSource{
+		FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+	},
+}
+
 // SidecarInjectField contains all info that will be injected
 type SidecarInjectField struct {
 	// determine whether to inject , default is not to inject
@@ -128,7 +138,8 @@ func (s *SidecarInjectField) Inject(pod *corev1.Pod) {
 
 		// envs to be append
 		var envsTBA []corev1.EnvVar
-		for j, envInject := range s.Envs {
+		envs := append(append([]corev1.EnvVar{}, s.Envs...), instanceNameEnv)
+		for j, envInject := range envs {
 			isExists := false
 			for _, envExists := range targetContainers[i].Env {
 				if strings.EqualFold(envExists.Name, envInject.Name) {
@@ -136,13 +147,17 @@ func (s *SidecarInjectField) Inject(pod *corev1.Pod) {
 					break
 				}
 			}
+			for _, envAdded := range envsTBA {
+				if strings.EqualFold(envAdded.Name, envInject.Name) {
+					isExists = true
+					break
+				}
+			}
 			if !isExists {
-				envsTBA = append(envsTBA, s.Envs[j])
+				envsTBA = append(envsTBA, envs[j])
 			}
 		}
-		if len(s.Envs) > 0 {
-			(*targetContainers[i]).Env = append((*targetContainers[i]).Env, envsTBA...)
-		}
+		(*targetContainers[i]).Env = append((*targetContainers[i]).Env, envsTBA...)
 	}
 }
 
@@ -29,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/metrics"
 )
 
 // log is for logging in this package.
@@ -78,7 +79,11 @@ func (r *JavaagentInjector) Handle(ctx context.Context, req admission.Request) a
 	// initialize InjectProcess as a call chain
 	ip := NewInjectProcess(ctx, s, anno, ao, swAgentL, pod, req, javaagentInjectorLog, r.Client)
 	// do real injection
-	return ip.Run()
+	resp := ip.Run()
+	if s.NeedInject {
+		metrics.RecordInjection(req.Namespace, resp.Allowed && strings.EqualFold(pod.Annotations[SidecarInjectSucceedAnno], "true"))
+	}
+	return resp
 }
 
 func (r *JavaagentInjector) findMatchedSwAgentL(ctx context.Context, req admission.Request, pod *corev1.Pod) *v1alpha1.SwAgentList {
@@ -0,0 +1,251 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package manifests
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+)
+
+// TemplatesLabel is the label of the ConfigMaps overriding the templates, whose value is the component
+const TemplatesLabel = "operator.skywalking.apache.org/templates"
+
+const (
+	overrideTypeDirectory = "Directory"
+	overrideTypeConfigMap = "ConfigMap"
+)
+
+var _ kubernetes.SnapshotRepo = &OverlayRepo{}
+
+// OverlayRepo layers the templates from a directory and the labeled ConfigMaps over the embedded ones of a component.
+// A template in the directory overrides the embedded one at the same path, e.g. <Dir>/oapserver/templates/deployment.yaml.
+// The data of a ConfigMap are templates named by the keys, which override the ones in the templates directory.
+// The ConfigMaps are layered in the order of their names, and a template not embedded is added.
+type OverlayRepo struct {
+	*AssetsRepo
+	Dir       string
+	Client    client.Reader
+	Namespace string
+}
+
+// layer is a set of templates overriding the embedded ones
+type layer struct {
+	override operatorv1alpha1.TemplateOverride
+	files    map[string][]byte
+}
+
+func NewOverlayRepo(component, dir string, c client.Reader, namespace string) *OverlayRepo {
+	return &OverlayRepo{AssetsRepo: NewRepo(component), Dir: dir, Client: c, Namespace: namespace}
+}
+
+// overlay serves the templates of the layers loaded once
+type overlay struct {
+	*AssetsRepo
+	layers []layer
+}
+
+var _ kubernetes.OverridableRepo = &overlay{}
+
+// ReadFile reads the template at path from the top most layer which contains it
+func (r *OverlayRepo) ReadFile(path string) ([]byte, error) {
+	o, err := r.snapshot(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return o.ReadFile(path)
+}
+
+// GetFilesRecursive returns the embedded templates under path, followed by the ones added by the layers
+func (r *OverlayRepo) GetFilesRecursive(path string) ([]string, error) {
+	o, err := r.snapshot(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return o.GetFilesRecursive(path)
+}
+
+// Overrides returns the sources of the templates overriding the embedded ones
+func (r *OverlayRepo) Overrides() ([]operatorv1alpha1.TemplateOverride, error) {
+	o, err := r.snapshot(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return o.Overrides()
+}
+
+// Snapshot loads the layers once, and returns the repo serving the templates of them. The templates rendered
+// for a CR and the overrides recorded in its status are consistent even if the layers change meanwhile.
+func (r *OverlayRepo) Snapshot(ctx context.Context) (kubernetes.Repo, error) {
+	return r.snapshot(ctx)
+}
+
+func (r *OverlayRepo) snapshot(ctx context.Context) (*overlay, error) {
+	ll, err := r.layers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &overlay{AssetsRepo: r.AssetsRepo, layers: ll}, nil
+}
+
+func (o *overlay) ReadFile(path string) ([]byte, error) {
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		if bb, ok := o.layers[i].files[path]; ok {
+			return bb, nil
+		}
+	}
+	return o.AssetsRepo.ReadFile(path)
+}
+
+func (o *overlay) GetFilesRecursive(path string) ([]string, error) {
+	out, err := o.AssetsRepo.GetFilesRecursive(path)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(out))
+	for _, f := range out {
+		existing[f] = true
+	}
+	prefix := filepath.Join(o.Root, path) + "/"
+	var added []string
+	for _, l := range o.layers {
+		for f := range l.files {
+			if strings.HasPrefix(f, prefix) && !existing[f] {
+				existing[f] = true
+				added = append(added, f)
+			}
+		}
+	}
+	sort.Strings(added)
+	return append(out, added...), nil
+}
+
+func (o *overlay) Overrides() ([]operatorv1alpha1.TemplateOverride, error) {
+	overrides := make([]operatorv1alpha1.TemplateOverride, 0, len(o.layers))
+	for _, l := range o.layers {
+		overrides = append(overrides, l.override)
+	}
+	return overrides, nil
+}
+
+func (r *OverlayRepo) layers(ctx context.Context) ([]layer, error) {
+	var ll []layer
+	if r.Dir != "" {
+		l, err := r.directoryLayer()
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			ll = append(ll, *l)
+		}
+	}
+	if r.Client != nil && r.Namespace != "" {
+		cl, err := r.configMapLayers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ll = append(ll, cl...)
+	}
+	return ll, nil
+}
+
+func (r *OverlayRepo) directoryLayer() (*layer, error) {
+	root := filepath.Join(r.Dir, r.Root)
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Dir, p)
+		if err != nil {
+			return err
+		}
+		bb, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = bb
+		return nil
+	})
+	if err != nil || len(files) < 1 {
+		return nil, err
+	}
+	l := newLayer(overrideTypeDirectory, root, files)
+	return &l, nil
+}
+
+func (r *OverlayRepo) configMapLayers(ctx context.Context) ([]layer, error) {
+	cms := core.ConfigMapList{}
+	if err := r.Client.List(ctx, &cms, client.InNamespace(r.Namespace),
+		client.MatchingLabels{TemplatesLabel: r.Root}); err != nil {
+		return nil, err
+	}
+	sort.Slice(cms.Items, func(i, j int) bool { return cms.Items[i].Name < cms.Items[j].Name })
+	ll := make([]layer, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		files := make(map[string][]byte, len(cm.Data))
+		for k, v := range cm.Data {
+			files[path.Join(r.Root, "templates", k)] = []byte(v)
+		}
+		ll = append(ll, newLayer(overrideTypeConfigMap, cm.Namespace+"/"+cm.Name, files))
+	}
+	return ll, nil
+}
+
+func newLayer(overrideType, name string, files map[string][]byte) layer {
+	names := make([]string, 0, len(files))
+	for f := range files {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	hash := fnv.New64a()
+	for _, f := range names {
+		_, _ = hash.Write([]byte(f))
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write(files[f])
+		_, _ = hash.Write([]byte{0})
+	}
+	return layer{
+		override: operatorv1alpha1.TemplateOverride{
+			Type:    overrideType,
+			Name:    name,
+			Version: hex.EncodeToString(hash.Sum(nil)),
+			Files:   names,
+		},
+		files: files,
+	}
+}
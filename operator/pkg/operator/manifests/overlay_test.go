@@ -0,0 +1,151 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package manifests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+)
+
+func TestOverlayRepo(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ui", "templates"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"deployment.yaml": "from directory",
+		"sidecar.yaml":    "added by directory",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "ui", "templates", name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := fake.NewClientBuilder().WithObjects(
+		&core.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ui-templates", Namespace: "swck", Labels: map[string]string{TemplatesLabel: "ui"}},
+			Data:       map[string]string{"sidecar.yaml": "from configmap"},
+		},
+		&core.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "oap-templates", Namespace: "swck", Labels: map[string]string{TemplatesLabel: "oapserver"}},
+			Data:       map[string]string{"deployment.yaml": "another component"},
+		},
+		&core.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ui-templates", Namespace: "default", Labels: map[string]string{TemplatesLabel: "ui"}},
+			Data:       map[string]string{"deployment.yaml": "another namespace"},
+		},
+	).Build()
+	r := NewOverlayRepo("ui", dir, c, "swck")
+
+	readTests := []struct {
+		path string
+		want string
+	}{
+		{path: "ui/templates/deployment.yaml", want: "from directory"},
+		{path: "ui/templates/sidecar.yaml", want: "from configmap"},
+	}
+	for _, tt := range readTests {
+		got, err := r.ReadFile(tt.path)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", tt.path, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("ReadFile(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	embedded, err := NewRepo("ui").ReadFile("ui/templates/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.ReadFile("ui/templates/service.yaml"); !reflect.DeepEqual(got, embedded) {
+		t.Errorf("ReadFile() should fall back to the embedded template")
+	}
+
+	files, err := r.GetFilesRecursive("templates")
+	if err != nil {
+		t.Fatalf("GetFilesRecursive() error = %v", err)
+	}
+	if files[len(files)-1] != "ui/templates/sidecar.yaml" {
+		t.Errorf("GetFilesRecursive() = %v, should end with the added template", files)
+	}
+
+	overrides, err := r.Overrides()
+	if err != nil {
+		t.Fatalf("Overrides() error = %v", err)
+	}
+	if len(overrides) != 2 {
+		t.Fatalf("Overrides() = %v, want 2 sources", overrides)
+	}
+	if overrides[0].Type != "Directory" || overrides[0].Name != filepath.Join(dir, "ui") ||
+		!reflect.DeepEqual(overrides[0].Files, []string{"ui/templates/deployment.yaml", "ui/templates/sidecar.yaml"}) {
+		t.Errorf("Overrides()[0] = %v", overrides[0])
+	}
+	if overrides[1].Type != "ConfigMap" || overrides[1].Name != "swck/ui-templates" || overrides[1].Version == "" {
+		t.Errorf("Overrides()[1] = %v", overrides[1])
+	}
+}
+
+func TestOverlayRepoSnapshot(t *testing.T) {
+	cm := &core.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ui-templates", Namespace: "swck", Labels: map[string]string{TemplatesLabel: "ui"}},
+		Data:       map[string]string{"deployment.yaml": "before"},
+	}
+	c := fake.NewClientBuilder().WithObjects(cm).Build()
+	ctx := context.Background()
+	snapshot, err := NewOverlayRepo("ui", "", c, "swck").Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	before, err := snapshot.(kubernetes.OverridableRepo).Overrides()
+	if err != nil {
+		t.Fatalf("Overrides() error = %v", err)
+	}
+
+	cm.Data = map[string]string{"deployment.yaml": "after", "sidecar.yaml": "added"}
+	if err := c.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := snapshot.ReadFile("ui/templates/deployment.yaml"); string(got) != "before" {
+		t.Errorf("ReadFile() = %s, want the template when the snapshot is taken", got)
+	}
+	files, err := snapshot.GetFilesRecursive("templates")
+	if err != nil {
+		t.Fatalf("GetFilesRecursive() error = %v", err)
+	}
+	for _, f := range files {
+		if f == "ui/templates/sidecar.yaml" {
+			t.Errorf("GetFilesRecursive() = %v, want the templates when the snapshot is taken", files)
+		}
+	}
+	after, err := snapshot.(kubernetes.OverridableRepo).Overrides()
+	if err != nil {
+		t.Fatalf("Overrides() error = %v", err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("Overrides() = %v, want %v", after, before)
+	}
+}
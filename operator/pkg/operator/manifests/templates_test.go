@@ -0,0 +1,180 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package manifests
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	operatorv1alpha1 "github.com/apache/skywalking-swck/operator/apis/operator/v1alpha1"
+	"github.com/apache/skywalking-swck/operator/pkg/kubernetes"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	horizon := "# users of horizon\n" +
+		"auth:\n" +
+		"  local:\n" +
+		"    users:\n" +
+		"      - name: admin # the administrator\n" +
+		"        password: \"p#ss\"\n" +
+		"oap:\n" +
+		"  queryUrl: http://oap:12800/graphql#query\n"
+	ui := &operatorv1alpha1.UI{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "skywalking"},
+		Spec:       operatorv1alpha1.UISpec{Kind: "horizon", Config: horizon},
+	}
+	tests := []struct {
+		name      string
+		manifest  func(t *testing.T) string
+		values    interface{}
+		wantKinds []string
+		wantErr   error
+		check     func(t *testing.T, objects []*unstructured.Unstructured)
+	}{
+		{
+			name:      "keep the values containing '#'",
+			manifest:  readManifest("ui/templates/configmap.yaml"),
+			values:    ui,
+			wantKinds: []string{"ConfigMap"},
+			check: func(t *testing.T, objects []*unstructured.Unstructured) {
+				data, _, _ := unstructured.NestedString(objects[0].Object, "data", "horizon.yaml")
+				if data != horizon {
+					t.Errorf("horizon.yaml = %q, want %q", data, horizon)
+				}
+			},
+		},
+		{
+			name:     "nothing is rendered",
+			manifest: readManifest("ui/templates/configmap.yaml"),
+			values:   &operatorv1alpha1.UI{Spec: operatorv1alpha1.UISpec{Kind: "booster"}},
+			wantErr:  kubernetes.ErrNothingLoaded,
+		},
+		{
+			name:      "default JAVA_OPTS of OAP",
+			manifest:  readManifest("oapserver/templates/deployment.yaml"),
+			values:    &operatorv1alpha1.OAPServer{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "skywalking"}},
+			wantKinds: []string{"Deployment"},
+			check:     checkEnv("JAVA_OPTS", "-Xmx2048M"),
+		},
+		{
+			name:     "JAVA_OPTS of OAP from the config",
+			manifest: readManifest("oapserver/templates/deployment.yaml"),
+			values: &operatorv1alpha1.OAPServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "skywalking"},
+				Spec: operatorv1alpha1.OAPServerSpec{
+					Config: []corev1.EnvVar{{Name: "JAVA_OPTS", Value: "-Xmx1g"}},
+				},
+			},
+			wantKinds: []string{"Deployment"},
+			check:     checkEnv("JAVA_OPTS", "-Xmx1g"),
+		},
+		{
+			name:     "integers are decoded as int64",
+			manifest: readManifest("oapserver/templates/deployment.yaml"),
+			values: &operatorv1alpha1.OAPServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "skywalking"},
+				Spec:       operatorv1alpha1.OAPServerSpec{Instances: 3},
+			},
+			wantKinds: []string{"Deployment"},
+			check: func(t *testing.T, objects []*unstructured.Unstructured) {
+				replicas, found, err := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+				if err != nil || !found || replicas != 3 {
+					t.Errorf("replicas = %d, found %v, err %v, want int64 3", replicas, found, err)
+				}
+			},
+		},
+		{
+			name: "multiple documents",
+			manifest: func(t *testing.T) string {
+				return "# license header\n" +
+					"---\n" +
+					"apiVersion: v1\n" +
+					"kind: ServiceAccount\n" +
+					"metadata:\n" +
+					"  name: {{ .Name }}\n" +
+					"---\n" +
+					"# nothing but comments\n" +
+					"---\n" +
+					"apiVersion: v1\n" +
+					"kind: List\n" +
+					"items:\n" +
+					"- apiVersion: v1\n" +
+					"  kind: ConfigMap\n" +
+					"  metadata:\n" +
+					"    name: {{ .Name }}-a\n" +
+					"- apiVersion: v1\n" +
+					"  kind: Secret\n" +
+					"  metadata:\n" +
+					"    name: {{ .Name }}-b\n"
+			},
+			values:    ui,
+			wantKinds: []string{"ServiceAccount", "ConfigMap", "Secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, objects, err := kubernetes.LoadTemplate(tt.manifest(t), tt.values, nil)
+			if err != tt.wantErr {
+				t.Fatalf("LoadTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(objects) != len(tt.wantKinds) {
+				t.Fatalf("LoadTemplate() got %d objects, want %d", len(objects), len(tt.wantKinds))
+			}
+			for i, o := range objects {
+				if o.GetKind() != tt.wantKinds[i] {
+					t.Errorf("LoadTemplate() objects[%d].kind = %v, want %v", i, o.GetKind(), tt.wantKinds[i])
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, objects)
+			}
+		})
+	}
+}
+
+func checkEnv(name string, want ...string) func(t *testing.T, objects []*unstructured.Unstructured) {
+	return func(t *testing.T, objects []*unstructured.Unstructured) {
+		containers, _, _ := unstructured.NestedSlice(objects[0].Object, "spec", "template", "spec", "containers")
+		var got []string
+		for _, c := range containers {
+			env, _, _ := unstructured.NestedSlice(c.(map[string]interface{}), "env")
+			for _, e := range env {
+				if e.(map[string]interface{})["name"] == name {
+					got = append(got, e.(map[string]interface{})["value"].(string))
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("env %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func readManifest(path string) func(t *testing.T) string {
+	return func(t *testing.T) string {
+		bb, err := NewRepo("").ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		return string(bb)
+	}
+}